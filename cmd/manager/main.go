@@ -13,6 +13,7 @@ import (
 
 	"github.com/accanto/assembly-operator/pkg/apis"
 	"github.com/accanto/assembly-operator/pkg/controller"
+	"github.com/accanto/assembly-operator/pkg/controller/assembly"
 	"github.com/accanto/assembly-operator/version"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
@@ -51,6 +52,9 @@ func main() {
 	// be added before calling pflag.Parse().
 	pflag.CommandLine.AddFlagSet(zap.FlagSet())
 
+	// Add the Assembly controller flag set to the CLI
+	pflag.CommandLine.AddFlagSet(assembly.FlagSet())
+
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
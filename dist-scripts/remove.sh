@@ -10,6 +10,7 @@ fi
 
 kubectl delete deployment assembly-operator $namespaceOpt
 kubectl delete cm assembly-operator-config $namespaceOpt
+kubectl delete secret assembly-operator-credentials $namespaceOpt
 kubectl delete role assembly-operator $namespaceOpt
 kubectl delete rolebinding assembly-operator $namespaceOpt
 kubectl delete serviceaccount assembly-operator $namespaceOpt
@@ -10,6 +10,7 @@ require (
 	github.com/operator-framework/operator-sdk v0.13.0
 	github.com/prometheus/common v0.6.0
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
 	gopkg.in/yaml.v2 v2.2.4
 	k8s.io/api v0.0.0
 	k8s.io/apimachinery v0.0.0
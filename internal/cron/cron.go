@@ -0,0 +1,178 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit on how far ahead Next looks for a time matching a schedule, so a schedule which never fires (e.g. "0 0 30 2 *") is reported rather than searched for forever
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField     = field{name: "minute", min: 0, max: 59}
+	hourField       = field{name: "hour", min: 0, max: 23}
+	dayOfMonthField = field{name: "day of month", min: 1, max: 31}
+	monthField      = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted for Sunday, as well as 0
+	dayOfWeekField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule is a parsed cron schedule, in the standard five field form: minute, hour, day of month, month and day of week
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// When both day fields are restricted a day matches if either matches, as in cron
+	dayOfMonthRestricted bool
+	dayOfWeekRestricted  bool
+}
+
+// Parse parses a five field cron schedule. Each field is "*", a value, a range ("1-5"), any of these with a step ("*/15", "0-30/10")
+// or a comma separated list of them. Months and days of the week may also be given by their three letter names
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron schedule %q: expected 5 fields (minute hour day-of-month month day-of-week) but found %d", spec, len(fields))
+	}
+	schedule := &Schedule{}
+	var err error
+	if schedule.minute, _, err = parseField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("Invalid cron schedule %q: %s", spec, err)
+	}
+	if schedule.hour, _, err = parseField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("Invalid cron schedule %q: %s", spec, err)
+	}
+	if schedule.dayOfMonth, schedule.dayOfMonthRestricted, err = parseField(fields[2], dayOfMonthField); err != nil {
+		return nil, fmt.Errorf("Invalid cron schedule %q: %s", spec, err)
+	}
+	if schedule.month, _, err = parseField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("Invalid cron schedule %q: %s", spec, err)
+	}
+	if schedule.dayOfWeek, schedule.dayOfWeekRestricted, err = parseField(fields[4], dayOfWeekField); err != nil {
+		return nil, fmt.Errorf("Invalid cron schedule %q: %s", spec, err)
+	}
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1 << 0
+	}
+	return schedule, nil
+}
+
+// parseField returns the set of values matched by a field, as a bit per value, and whether the field restricts the values (is not "*")
+func parseField(value string, f field) (bits uint64, restricted bool, err error) {
+	for _, part := range strings.Split(value, ",") {
+		partBits, err := parsePart(part, f)
+		if err != nil {
+			return 0, false, err
+		}
+		bits |= partBits
+	}
+	return bits, !strings.HasPrefix(value, "*"), nil
+}
+
+func parsePart(part string, f field) (uint64, error) {
+	rangePart := part
+	step := 1
+	if slash := strings.Index(part, "/"); slash >= 0 {
+		var err error
+		step, err = strconv.Atoi(part[slash+1:])
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+		}
+		rangePart = part[:slash]
+	}
+	start, end := f.min, f.max
+	switch {
+	case rangePart == "*":
+	case strings.Contains(rangePart, "-"):
+		bounds := strings.SplitN(rangePart, "-", 2)
+		var err error
+		if start, err = parseValue(bounds[0], f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(bounds[1], f); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+		}
+	default:
+		var err error
+		if start, err = parseValue(rangePart, f); err != nil {
+			return 0, err
+		}
+		if rangePart == part {
+			// A single value, without a step
+			end = start
+		}
+	}
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << uint(value)
+	}
+	return bits, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	if named, ok := f.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < f.min || parsed > f.max {
+		return 0, fmt.Errorf("invalid %s %q, expected a value from %d to %d", f.name, value, f.min, f.max)
+	}
+	return parsed, nil
+}
+
+func (schedule *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := schedule.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := schedule.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if schedule.dayOfMonthRestricted && schedule.dayOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// Next returns the first time after the given time matched by the schedule, evaluated in the location of the given time.
+// The zero time is returned if the schedule does not match any time in the next 5 years
+func (schedule *Schedule) Next(after time.Time) time.Time {
+	location := after.Location()
+	// Truncated as an instant, rather than rebuilt with time.Date, so a time repeated when clocks go back is not seen twice
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(searchLimit)
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case schedule.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, location)
+		case !schedule.matchesDay(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+		case schedule.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, location)
+		case schedule.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		if !next.After(t) {
+			// Moving to the start of the next hour or day can land on an earlier time when clocks go back
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
@@ -0,0 +1,158 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accanto/assembly-operator/internal/cron"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("Time zone %s is not available: %s", name, err)
+	}
+	return location
+}
+
+func TestParseRejectsInvalidSchedules(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+	}{
+		{name: "too few fields", schedule: "0 3 * *"},
+		{name: "too many fields", schedule: "0 3 * * * *"},
+		{name: "minute out of range", schedule: "60 3 * * *"},
+		{name: "day of month out of range", schedule: "0 3 0 * *"},
+		{name: "day of week out of range", schedule: "0 3 * * 8"},
+		{name: "unknown name", schedule: "0 3 * foo *"},
+		{name: "zero step", schedule: "*/0 3 * * *"},
+		{name: "invalid step", schedule: "*/x 3 * * *"},
+		{name: "reversed range", schedule: "0 5-3 * * *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := cron.Parse(tt.schedule); err == nil {
+				t.Errorf("Expected %q to be rejected", tt.schedule)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2026-02-01 is a Sunday
+	after := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule string
+		after    time.Time
+		expected time.Time
+	}{
+		{name: "daily", schedule: "0 3 * * *", after: after, expected: time.Date(2026, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{name: "strictly after", schedule: "0 0 * * *", after: after, expected: time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "seconds ignored", schedule: "1 0 * * *", after: after.Add(30 * time.Second), expected: time.Date(2026, 2, 1, 0, 1, 0, 0, time.UTC)},
+		{name: "step", schedule: "*/15 * * * *", after: after.Add(20 * time.Minute), expected: time.Date(2026, 2, 1, 0, 30, 0, 0, time.UTC)},
+		{name: "range with step", schedule: "0 1-9/4 * * *", after: after.Add(2 * time.Hour), expected: time.Date(2026, 2, 1, 5, 0, 0, 0, time.UTC)},
+		{name: "list", schedule: "0 0 10,20 * *", after: time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "month names", schedule: "0 0 1 apr,OCT *", after: after, expected: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day names", schedule: "0 0 * * wed", after: after, expected: time.Date(2026, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{name: "7 is Sunday", schedule: "0 0 * * 7", after: after, expected: time.Date(2026, 2, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "range to 7 includes Sunday", schedule: "0 0 * * 6-7", after: time.Date(2026, 2, 7, 12, 0, 0, 0, time.UTC), expected: time.Date(2026, 2, 8, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted, so the 13th or any Friday
+		{name: "day of month or day of week", schedule: "0 0 13 * 5", after: after, expected: time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month or day of week, day of month first", schedule: "0 0 13 * 5", after: time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)},
+		// A step from "*" is unrestricted, so both day fields must match: an odd day which is a Monday
+		{name: "step from * is unrestricted", schedule: "0 0 */2 * 1", after: after, expected: time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month and any day of week", schedule: "0 0 13 * *", after: after, expected: time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day within limit", schedule: "0 0 29 2 *", after: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never matches", schedule: "0 0 30 2 *", after: after},
+		// 2100 is not a leap year, so there are 8 years between the leap days either side of it
+		{name: "beyond limit", schedule: "0 0 29 2 *", after: time.Date(2096, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "within limit", schedule: "0 0 29 2 *", after: time.Date(2099, 3, 1, 0, 0, 0, 0, time.UTC), expected: time.Date(2104, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.Parse(tt.schedule)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %s", tt.schedule, err)
+			}
+			if next := schedule.Next(tt.after); !next.Equal(tt.expected) {
+				t.Errorf("Expected the time after %s to be %s but was %s", tt.after, tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestNextAcrossClockChanges(t *testing.T) {
+	london := mustLoadLocation(t, "Europe/London")
+	tests := []struct {
+		name     string
+		schedule string
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "local time",
+			schedule: "0 3 * * *",
+			after:    time.Date(2026, 6, 1, 12, 0, 0, 0, london),
+			expected: time.Date(2026, 6, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			// Clocks go forward from 01:00 to 02:00 on 29 March, so 01:30 does not happen that day
+			name:     "skipped when clocks go forward",
+			schedule: "30 1 * * *",
+			after:    time.Date(2026, 3, 28, 12, 0, 0, 0, london),
+			expected: time.Date(2026, 3, 30, 0, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "after clocks go forward",
+			schedule: "30 2 * * *",
+			after:    time.Date(2026, 3, 28, 12, 0, 0, 0, london),
+			expected: time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "hourly when clocks go forward",
+			schedule: "0 * * * *",
+			after:    time.Date(2026, 3, 29, 0, 30, 0, 0, london),
+			expected: time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "after clocks go back",
+			schedule: "30 2 * * *",
+			after:    time.Date(2026, 10, 24, 12, 0, 0, 0, london),
+			expected: time.Date(2026, 10, 25, 2, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.Parse(tt.schedule)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %s", tt.schedule, err)
+			}
+			next := schedule.Next(tt.after)
+			if !next.Equal(tt.expected) {
+				t.Errorf("Expected the time after %s to be %s but was %s", tt.after, tt.expected, next)
+			}
+			if next.Location() != london {
+				t.Errorf("Expected the time to be in the location of the time it follows but was %s", next.Location())
+			}
+		})
+	}
+}
+
+func TestNextOnceWhenClocksGoBack(t *testing.T) {
+	london := mustLoadLocation(t, "Europe/London")
+	// Clocks go back from 02:00 to 01:00 on 25 October, so 01:30 happens twice that day
+	schedule, err := cron.Parse("30 1 * * *")
+	if err != nil {
+		t.Fatalf("Failed to parse schedule: %s", err)
+	}
+	var matches []time.Time
+	for next := schedule.Next(time.Date(2026, 10, 24, 12, 0, 0, 0, london)); next.Before(time.Date(2026, 10, 27, 0, 0, 0, 0, london)); next = schedule.Next(next) {
+		matches = append(matches, next)
+	}
+	if len(matches) != 2 || matches[0].Day() != 25 || matches[1].Day() != 26 {
+		t.Errorf("Expected a single match on each day but found %v", matches)
+	}
+}
@@ -1,10 +1,10 @@
 package lm
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"strings"
 	"time"
 
@@ -15,40 +15,85 @@ import (
 
 var clientLog = logf.Log.WithName("lm_client")
 
+// LMClient is the set of LM operations used by the operator to manage Assemblies. Implementations must be safe for concurrent use,
+// as Assemblies may be reconciled in parallel
+type LMClient interface {
+	CreateAssembly(createRequest CreateAssemblyRequest) (processID string, err error)
+	UpgradeAssembly(upgradeRequest UpgradeAssemblyRequest) (processID string, err error)
+	ChangeAssemblyState(changeStateRequest ChangeAssemblyStateRequest) (processID string, err error)
+	DeleteAssembly(deleteRequest DeleteAssemblyRequest) (processID string, err error)
+	GetAssemblyByID(assemblyID string) (*Assembly, bool, error)
+	GetAssemblyByName(assemblyName string) (*Assembly, bool, error)
+	GetLatestProcess(assemblyName string) (*Process, bool, error)
+	GetProcessByID(processID string) (*Process, bool, error)
+}
+
+// blank assignment to verify that LMRestClient implements LMClient
+var _ LMClient = &LMRestClient{}
+
 // --LM Client--
-type LMClient struct {
+
+// LMRestClient is safe for concurrent use: requests share the resty client and the access token held by the LMSecurityCtrl
+type LMRestClient struct {
 	restClient      *resty.Client
 	lmConfiguration *LMConfiguration
 	securityCtrl    *LMSecurityCtrl
 }
 
-func BuildClient(lmConfiguration *LMConfiguration) *LMClient {
+func BuildClient(lmConfiguration *LMConfiguration) (*LMRestClient, error) {
 	clientLog.Info("Building LM client", LogKeys.URL, lmConfiguration.Base, LogKeys.Client, lmConfiguration.Client, LogKeys.Secure, lmConfiguration.Secure)
-	lmSecurityCtrl := BuildCtrl(lmConfiguration)
+	tlsConfig, err := buildTLSConfig(lmConfiguration)
+	if err != nil {
+		clientLog.Error(err, "Invalid TLS configuration")
+		return nil, err
+	}
+	lmSecurityCtrl := BuildCtrl(lmConfiguration, tlsConfig)
 	restClient := resty.New()
-	restClient.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	restClient.SetTLSClientConfig(tlsConfig)
 	restClient.SetTimeout(2 * time.Minute)
-	return &LMClient{
+	return &LMRestClient{
 		restClient:      restClient,
 		lmConfiguration: lmConfiguration,
 		securityCtrl:    lmSecurityCtrl,
+	}, nil
+}
+
+// Close stops background work, such as refreshing access tokens, started by the client
+func (client *LMRestClient) Close() {
+	client.securityCtrl.stop()
+}
+
+// execute sends a request to LM with authentication headers, retrying transient failures according to the configured RetryConfiguration.
+// When LM rejects the access token (401) it is discarded and the request is sent once more with a newly obtained token
+func (client *LMRestClient) execute(method string, url string, prepare func(request *resty.Request)) (*resty.Response, error) {
+	return client.lmConfiguration.Retry.executeWithRetry(method, url, func() (*resty.Response, error) {
+		return client.executeAuthenticated(method, url, prepare)
+	})
+}
+
+func (client *LMRestClient) executeAuthenticated(method string, url string, prepare func(request *resty.Request)) (*resty.Response, error) {
+	resp, accessToken, err := client.send(method, url, prepare)
+	if err == nil && resp.StatusCode() == http.StatusUnauthorized && client.lmConfiguration.Secure {
+		clientLog.Info("Request was unauthorized, retrying with a new access token", LogKeys.URL, url)
+		client.securityCtrl.invalidateToken(accessToken)
+		resp, _, err = client.send(method, url, prepare)
 	}
+	return resp, err
 }
 
-func (client *LMClient) addAuthenticationHeaders(request *resty.Request) error {
+func (client *LMRestClient) send(method string, url string, prepare func(request *resty.Request)) (*resty.Response, string, error) {
 	accessToken, err := client.securityCtrl.getAccessToken()
 	if err != nil {
 		clientLog.Error(err, "Unable to get access token")
-		return err
+		return nil, "", err
 	}
-	request.SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-	return nil
-}
-
-func (client *LMClient) startRequest() (*resty.Request, error) {
-	request := client.restClient.R()
-	err := client.addAuthenticationHeaders(request)
-	return request, err
+	request := client.restClient.R().
+		EnableTrace().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	prepare(request)
+	resp, err := request.Execute(method, url)
+	return resp, accessToken, err
 }
 
 // API methods
@@ -59,20 +104,14 @@ const upgradeAssemblyAPI = "/api/intent/upgradeAssembly"
 const assemblyTopologyAPI = "/api/topology/assemblies"
 const processAPI = "/api/processes"
 
-func (client *LMClient) executeProcess(requestJSON string, processAPI string, processType string) (processID string, err error) {
+func (client *LMRestClient) executeProcess(requestJSON string, processAPI string, processType string) (processID string, err error) {
 	url := fmt.Sprintf("%s%s", client.lmConfiguration.Base, processAPI)
-	requestLogger := clientLog.WithValues(LogKeys.URL, url, LogKeys.Body, requestJSON)
+	// The body is not logged, as it holds property values which may have been read from Secrets
+	requestLogger := clientLog.WithValues(LogKeys.URL, url)
 	requestLogger.Info(fmt.Sprintf("Sending request: %s", processType))
-	req, err := client.startRequest()
-	if err != nil {
-		requestLogger.Error(err, fmt.Sprintf("Unable to build request"))
-		return "", err
-	}
-	resp, err := req.
-		EnableTrace().
-		SetBody(requestJSON).
-		SetHeader("Content-Type", "application/json").
-		Post(url)
+	resp, err := client.execute(resty.MethodPost, url, func(request *resty.Request) {
+		request.SetBody(requestJSON)
+	})
 	if err != nil {
 		requestLogger.Error(err, fmt.Sprintf("Unable to %s", processType))
 		return "", err
@@ -91,7 +130,7 @@ func (client *LMClient) executeProcess(requestJSON string, processAPI string, pr
 	return processID, nil
 }
 
-func (client *LMClient) CreateAssembly(createRequest CreateAssemblyRequest) (processID string, err error) {
+func (client *LMRestClient) CreateAssembly(createRequest CreateAssemblyRequest) (processID string, err error) {
 	bytes, err := json.Marshal(createRequest)
 	if err != nil {
 		clientLog.Error(err, "Unable to parse JSON for Create Assembly request")
@@ -101,7 +140,7 @@ func (client *LMClient) CreateAssembly(createRequest CreateAssemblyRequest) (pro
 	return client.executeProcess(requestJSON, createAssemblyAPI, "Create Assembly")
 }
 
-func (client *LMClient) UpgradeAssembly(upgradeRequest UpgradeAssemblyRequest) (processID string, err error) {
+func (client *LMRestClient) UpgradeAssembly(upgradeRequest UpgradeAssemblyRequest) (processID string, err error) {
 	bytes, err := json.Marshal(upgradeRequest)
 	if err != nil {
 		clientLog.Error(err, "Unable to parse JSON for Upgrade Assembly request")
@@ -111,7 +150,7 @@ func (client *LMClient) UpgradeAssembly(upgradeRequest UpgradeAssemblyRequest) (
 	return client.executeProcess(requestJSON, upgradeAssemblyAPI, "Upgrade Assembly")
 }
 
-func (client *LMClient) ChangeAssemblyState(changeStateRequest ChangeAssemblyStateRequest) (processID string, err error) {
+func (client *LMRestClient) ChangeAssemblyState(changeStateRequest ChangeAssemblyStateRequest) (processID string, err error) {
 	bytes, err := json.Marshal(changeStateRequest)
 	if err != nil {
 		clientLog.Error(err, "Unable to parse JSON for Change Assembly State request")
@@ -121,7 +160,7 @@ func (client *LMClient) ChangeAssemblyState(changeStateRequest ChangeAssemblySta
 	return client.executeProcess(requestJSON, changeAssemblyStateAPI, "Change Assembly State")
 }
 
-func (client *LMClient) DeleteAssembly(deleteRequest DeleteAssemblyRequest) (processID string, err error) {
+func (client *LMRestClient) DeleteAssembly(deleteRequest DeleteAssemblyRequest) (processID string, err error) {
 	bytes, err := json.Marshal(deleteRequest)
 	if err != nil {
 		clientLog.Error(err, "Unable to parse JSON for Delete Assembly request")
@@ -131,21 +170,14 @@ func (client *LMClient) DeleteAssembly(deleteRequest DeleteAssemblyRequest) (pro
 	return client.executeProcess(requestJSON, deleteAssemblyAPI, "Delete Assembly")
 }
 
-func (client *LMClient) GetAssemblyByID(assemblyID string) (*Assembly, bool, error) {
+func (client *LMRestClient) GetAssemblyByID(assemblyID string) (*Assembly, bool, error) {
 	url := fmt.Sprintf("%s%s/%s", client.lmConfiguration.Base, assemblyTopologyAPI, assemblyID)
 	requestLogger := clientLog.WithValues(LogKeys.URL, url, LogKeys.AssemblyID, assemblyID)
 	requestLogger.Info("Sending request to retrieve Assembly instance by ID")
 	result := &Assembly{}
-	req, err := client.startRequest()
-	if err != nil {
-		requestLogger.Error(err, fmt.Sprintf("Unable to build request"))
-		return result, false, err
-	}
-	resp, err := req.
-		EnableTrace().
-		SetResult(result).
-		SetHeader("Content-Type", "application/json").
-		Get(url)
+	resp, err := client.execute(resty.MethodGet, url, func(request *resty.Request) {
+		request.SetResult(result)
+	})
 	if err != nil {
 		requestLogger.Error(err, "Unable to retrieve Assembly")
 		return result, false, err
@@ -164,21 +196,14 @@ func (client *LMClient) GetAssemblyByID(assemblyID string) (*Assembly, bool, err
 	}
 }
 
-func (client *LMClient) GetAssemblyByName(assemblyName string) (*Assembly, bool, error) {
-	url := fmt.Sprintf("%s%s?name=%s", client.lmConfiguration.Base, assemblyTopologyAPI, assemblyName)
+func (client *LMRestClient) GetAssemblyByName(assemblyName string) (*Assembly, bool, error) {
+	url := fmt.Sprintf("%s%s?name=%s", client.lmConfiguration.Base, assemblyTopologyAPI, neturl.QueryEscape(assemblyName))
 	requestLogger := clientLog.WithValues(LogKeys.URL, url, LogKeys.AssemblyName, assemblyName)
 	requestLogger.Info("Sending request to retrieve Assembly instance by name")
 	result := make([]Assembly, 1)
-	req, err := client.startRequest()
-	if err != nil {
-		requestLogger.Error(err, fmt.Sprintf("Unable to build request"))
-		return &Assembly{}, false, err
-	}
-	resp, err := req.
-		EnableTrace().
-		SetResult(result).
-		SetHeader("Content-Type", "application/json").
-		Get(url)
+	resp, err := client.execute(resty.MethodGet, url, func(request *resty.Request) {
+		request.SetResult(result)
+	})
 	if err != nil {
 		requestLogger.Error(err, "Unable to retrieve Assembly")
 		return &Assembly{}, false, err
@@ -200,21 +225,14 @@ func (client *LMClient) GetAssemblyByName(assemblyName string) (*Assembly, bool,
 	}
 }
 
-func (client *LMClient) GetLatestProcess(assemblyName string) (*Process, bool, error) {
-	url := fmt.Sprintf("%s%s?assemblyName=%s&limit=1", client.lmConfiguration.Base, processAPI, assemblyName)
+func (client *LMRestClient) GetLatestProcess(assemblyName string) (*Process, bool, error) {
+	url := fmt.Sprintf("%s%s?assemblyName=%s&limit=1", client.lmConfiguration.Base, processAPI, neturl.QueryEscape(assemblyName))
 	requestLogger := clientLog.WithValues(LogKeys.URL, url, LogKeys.AssemblyName, assemblyName)
 	requestLogger.Info("Sending request to retrieve latest Process instance for Assembly")
 	result := make([]Process, 1)
-	req, err := client.startRequest()
-	if err != nil {
-		requestLogger.Error(err, "Unable to build request")
-		return &Process{}, false, err
-	}
-	resp, err := req.
-		EnableTrace().
-		SetResult(result).
-		SetHeader("Content-Type", "application/json").
-		Get(url)
+	resp, err := client.execute(resty.MethodGet, url, func(request *resty.Request) {
+		request.SetResult(result)
+	})
 	if err != nil {
 		requestLogger.Error(err, "Unable to retrieve latest Process")
 		return &Process{}, false, err
@@ -236,21 +254,14 @@ func (client *LMClient) GetLatestProcess(assemblyName string) (*Process, bool, e
 	}
 }
 
-func (client *LMClient) GetProcessByID(processID string) (*Process, bool, error) {
+func (client *LMRestClient) GetProcessByID(processID string) (*Process, bool, error) {
 	url := fmt.Sprintf("%s%s/%s", client.lmConfiguration.Base, processAPI, processID)
 	requestLogger := clientLog.WithValues(LogKeys.URL, url, LogKeys.ProcessID, processID)
 	requestLogger.Info("Sending request to retrieve Process by ID")
 	result := &Process{}
-	req, err := client.startRequest()
-	if err != nil {
-		requestLogger.Error(err, fmt.Sprintf("Unable to build request"))
-		return result, false, err
-	}
-	resp, err := req.
-		EnableTrace().
-		SetResult(result).
-		SetHeader("Content-Type", "application/json").
-		Get(url)
+	resp, err := client.execute(resty.MethodGet, url, func(request *resty.Request) {
+		request.SetResult(result)
+	})
 	if err != nil {
 		requestLogger.Error(err, "Unable to retrieve Process by ID")
 		return result, false, err
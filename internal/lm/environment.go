@@ -1,7 +1,10 @@
 package lm
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -9,25 +12,75 @@ import (
 
 var environmentLog = logf.Log.WithName("lm_environment")
 
+// DefaultConfigurationPath is the location of the operator configuration, mounted from the assembly-operator-config ConfigMap
+const DefaultConfigurationPath = "/var/assembly-operator/config.yaml"
+
+// DefaultClientSecretPath is the location of the LM client secret, mounted from the assembly-operator-credentials Secret
+const DefaultClientSecretPath = "/var/assembly-operator/clientSecret"
+
 type LMConfiguration struct {
-	Client       string `yaml:"client"`
-	ClientSecret string `yaml:"clientSecret"`
-	Base         string `yaml:"base"`
-	Secure       bool   `yaml:"secure"`
+	Client string `yaml:"client"`
+	// Deprecated: only used when no file exists at ClientSecretFile. Keep the client secret in a Secret instead
+	ClientSecret     string           `yaml:"clientSecret"`
+	ClientSecretFile string           `yaml:"clientSecretFile"`
+	Base             string           `yaml:"base"`
+	Secure           bool             `yaml:"secure"`
+	TLS              TLSConfiguration `yaml:"tls"`
+	// Fraction of an access token's lifetime after which it is refreshed in the background (defaults to 0.8)
+	TokenRefreshRatio float64 `yaml:"tokenRefreshRatio"`
+	// Retry policy for transient failures. Settings missing from the config file take the values of DefaultRetryConfiguration
+	Retry RetryConfiguration `yaml:"retry"`
+}
+
+// TLSConfiguration controls verification of the LM server certificate and the client certificate presented to LM.
+// Files are re-read when they change on disk.
+type TLSConfiguration struct {
+	// Path to a PEM bundle of CA certificates trusted to sign the LM server certificate (defaults to the system roots)
+	CABundle string `yaml:"caBundle"`
+	// Path to a PEM client certificate presented to LM for mutual TLS (requires ClientKey)
+	ClientCertificate string `yaml:"clientCertificate"`
+	// Path to the PEM private key of ClientCertificate
+	ClientKey string `yaml:"clientKey"`
+	// Overrides the server name used for SNI and to verify the LM server certificate (defaults to the host of Base)
+	ServerName string `yaml:"serverName"`
+	// Minimum TLS version accepted: 1.0, 1.1, 1.2 or 1.3 (defaults to 1.2)
+	MinVersion string `yaml:"minVersion"`
+	// Disables verification of the LM server certificate. Only intended for development environments
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
 }
 
 func ReadLMConfiguration() (*LMConfiguration, error) {
-	yamlFile, err := ioutil.ReadFile("/var/assembly-operator/config.yaml")
+	return ReadLMConfigurationFrom(DefaultConfigurationPath)
+}
+
+// ReadLMConfigurationFrom reads the configuration file at the given path, taking the client secret from ClientSecretFile
+func ReadLMConfigurationFrom(configurationPath string) (*LMConfiguration, error) {
+	yamlFile, err := ioutil.ReadFile(configurationPath)
 	if err != nil {
 		environmentLog.Error(err, "Failed to read config file")
 		return &LMConfiguration{}, err
 	}
-	configuration := LMConfiguration{}
+	configuration := LMConfiguration{Retry: DefaultRetryConfiguration()}
 	err = yaml.Unmarshal(yamlFile, &configuration)
 	if err != nil {
 		environmentLog.Error(err, "Unmarshal error")
 		return &configuration, err
 	}
+	if configuration.ClientSecretFile == "" {
+		configuration.ClientSecretFile = DefaultClientSecretPath
+	}
+
+	clientSecret, err := ioutil.ReadFile(configuration.ClientSecretFile)
+	if err == nil {
+		configuration.ClientSecret = strings.TrimSpace(string(clientSecret))
+	} else if !os.IsNotExist(err) {
+		environmentLog.Error(err, "Failed to read client secret file", "path", configuration.ClientSecretFile)
+		return &configuration, err
+	} else if configuration.ClientSecret != "" {
+		environmentLog.Info("WARNING: clientSecret is set in plaintext in the config file, move it to a Secret mounted at clientSecretFile", "path", configuration.ClientSecretFile)
+	} else if configuration.Secure {
+		return &configuration, fmt.Errorf("LM is secure but no client secret was found at %s", configuration.ClientSecretFile)
+	}
 
 	return &configuration, nil
 }
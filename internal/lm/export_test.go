@@ -0,0 +1,8 @@
+package lm
+
+import "time"
+
+// SetReloadInterval changes how often a ReloadingLMClient checks for changes, so tests do not wait for the default interval
+func SetReloadInterval(c *ReloadingLMClient, interval time.Duration) {
+	c.interval = interval
+}
@@ -0,0 +1,555 @@
+// Package fake provides an in-process LM server, built on httptest, which simulates the
+// intent, topology, process and OAuth APIs used by the operator so reconcile logic can be tested offline.
+package fake
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+)
+
+// Intent types as reported on LM processes
+const (
+	CreateAssemblyIntent      = "CreateAssembly"
+	UpgradeAssemblyIntent     = "UpgradeAssembly"
+	ChangeAssemblyStateIntent = "ChangeAssemblyState"
+	DeleteAssemblyIntent      = "DeleteAssembly"
+)
+
+const (
+	intentAPIPrefix     = "/api/intent/"
+	assemblyTopologyAPI = "/api/topology/assemblies"
+	processAPI          = "/api/processes"
+	oauthAPI            = "/oauth/token"
+)
+
+// ProcessLifecycle describes the statuses a process moves through after an intent is accepted.
+// Each retrieval of the process by ID, and each call to Tick, reports the current status and then moves it on to the next step.
+// Listing processes reports their current status without moving them on.
+type ProcessLifecycle struct {
+	// Statuses reported, in order, before the final status is reached (e.g. "Pending", "In Progress")
+	Steps []string
+	// Status the process ends in, either "Completed", "Failed" or "Cancelled"
+	FinalStatus string
+	// Reason reported with the final status, usually only set when Failed
+	StatusReason string
+}
+
+// DefaultProcessLifecycle is used for any intent type without a lifecycle configured through SetProcessLifecycle
+var DefaultProcessLifecycle = ProcessLifecycle{
+	Steps:       []string{"Pending", "In Progress"},
+	FinalStatus: "Completed",
+}
+
+// RecordedRequest details a request received by the Server
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   string
+}
+
+type process struct {
+	lm.Process
+	assemblyName string
+	lifecycle    ProcessLifecycle
+	step         int
+	complete     func()
+}
+
+type injectedError struct {
+	method     string
+	pathPrefix string
+	statusCode int
+	retryAfter string
+	remaining  int
+}
+
+// Server simulates an LM environment. Create one with NewServer and Close it when finished.
+type Server struct {
+	server *httptest.Server
+	// URL of the Server, in the form of http://ipaddr:port (https with NewTLSServer) with no trailing slash
+	URL string
+
+	mu             sync.Mutex
+	nextID         int
+	assemblies     map[string]*lm.Assembly
+	processes      map[string]*process
+	processOrder   []string
+	lifecycles     map[string]ProcessLifecycle
+	injectedErrors []*injectedError
+	requests       []RecordedRequest
+	secure         bool
+	client         string
+	clientSecret   string
+	tokenExpiresIn int32
+	issuedTokens   map[string]bool
+}
+
+// NewServer starts a Server with security disabled. Use EnableSecurity to require OAuth tokens.
+func NewServer() *Server {
+	s := newServer()
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	return s
+}
+
+// NewTLSServer starts a Server, with security disabled, which serves HTTPS with the self-signed certificate returned by Certificate
+func NewTLSServer() *Server {
+	s := newServer()
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	return s
+}
+
+func newServer() *Server {
+	return &Server{
+		assemblies:     make(map[string]*lm.Assembly),
+		processes:      make(map[string]*process),
+		lifecycles:     make(map[string]ProcessLifecycle),
+		issuedTokens:   make(map[string]bool),
+		tokenExpiresIn: 3600,
+	}
+}
+
+// Certificate returns the certificate of a Server started with NewTLSServer, which is also the CA certificate needed to verify it
+func (s *Server) Certificate() *x509.Certificate {
+	return s.server.Certificate()
+}
+
+// Close shuts down the Server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Configuration returns an LMConfiguration suitable for building a client which talks to this Server
+func (s *Server) Configuration() *lm.LMConfiguration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &lm.LMConfiguration{
+		Base:         s.URL,
+		Client:       s.client,
+		ClientSecret: s.clientSecret,
+		Secure:       s.secure,
+	}
+}
+
+// EnableSecurity requires all API requests to carry a bearer token obtained from /oauth/token with the given client credentials
+func (s *Server) EnableSecurity(client string, clientSecret string, tokenExpiresIn int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secure = true
+	s.client = client
+	s.clientSecret = clientSecret
+	s.tokenExpiresIn = tokenExpiresIn
+}
+
+// RevokeTokens invalidates all previously issued access tokens
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issuedTokens = make(map[string]bool)
+}
+
+// SetProcessLifecycle configures the lifecycle of processes created for the given intent type (e.g. CreateAssemblyIntent)
+func (s *Server) SetProcessLifecycle(intentType string, lifecycle ProcessLifecycle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lifecycle.FinalStatus == "" {
+		lifecycle.FinalStatus = "Completed"
+	}
+	s.lifecycles[intentType] = lifecycle
+}
+
+// InjectError makes the next count requests matching the method and path prefix fail with the given status code
+func (s *Server) InjectError(method string, pathPrefix string, statusCode int, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injectedErrors = append(s.injectedErrors, &injectedError{
+		method:     method,
+		pathPrefix: pathPrefix,
+		statusCode: statusCode,
+		remaining:  count,
+	})
+}
+
+// InjectRetryAfter makes the next count requests matching the method and path prefix fail with the given status code and Retry-After header
+func (s *Server) InjectRetryAfter(method string, pathPrefix string, statusCode int, retryAfter string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injectedErrors = append(s.injectedErrors, &injectedError{
+		method:     method,
+		pathPrefix: pathPrefix,
+		statusCode: statusCode,
+		retryAfter: retryAfter,
+		remaining:  count,
+	})
+}
+
+// AddAssembly adds an Assembly directly, as if it was created outside of the operator, and returns its ID
+func (s *Server) AddAssembly(assembly lm.Assembly) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if assembly.ID == "" {
+		assembly.ID = s.newID()
+	}
+	s.assemblies[assembly.ID] = &assembly
+	return assembly.ID
+}
+
+// GetAssembly returns a copy of the named Assembly
+func (s *Server) GetAssembly(assemblyName string) (lm.Assembly, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assembly := s.findAssemblyByName(assemblyName)
+	if assembly == nil {
+		return lm.Assembly{}, false
+	}
+	return copyAssembly(assembly), true
+}
+
+// GetProcess returns a copy of the process with the given ID
+func (s *Server) GetProcess(processID string) (lm.Process, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.processes[processID]
+	if !ok {
+		return lm.Process{}, false
+	}
+	return p.Process, true
+}
+
+// Tick moves every process which has not finished on to the next step of its lifecycle, as if it had been retrieved by ID
+func (s *Server) Tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.processOrder {
+		s.processes[id].observe()
+	}
+}
+
+// Requests returns all requests received by the Server so far
+func (s *Server) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]RecordedRequest, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// IntentRequests returns the requests received by the Server for the given intent API (e.g. "createAssembly")
+func (s *Server) IntentRequests(intent string) []RecordedRequest {
+	var matching []RecordedRequest
+	for _, request := range s.Requests() {
+		if request.Method == http.MethodPost && request.Path == intentAPIPrefix+intent {
+			matching = append(matching, request)
+		}
+	}
+	return matching
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body := ""
+	if r.Body != nil {
+		if bytes, err := ioutil.ReadAll(r.Body); err == nil {
+			body = string(bytes)
+		}
+	}
+	s.requests = append(s.requests, RecordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Body: body})
+
+	for _, injected := range s.injectedErrors {
+		if injected.remaining > 0 && injected.method == r.Method && strings.HasPrefix(r.URL.Path, injected.pathPrefix) {
+			injected.remaining--
+			if injected.retryAfter != "" {
+				w.Header().Set("Retry-After", injected.retryAfter)
+			}
+			writeError(w, injected.statusCode, "Injected error")
+			return
+		}
+	}
+
+	if r.URL.Path == oauthAPI {
+		s.handleToken(w, r)
+		return
+	}
+
+	if s.secure && !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "Full authentication is required to access this resource")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, intentAPIPrefix):
+		s.handleIntent(w, strings.TrimPrefix(r.URL.Path, intentAPIPrefix), body)
+	case r.Method == http.MethodGet && r.URL.Path == assemblyTopologyAPI:
+		s.handleFindAssemblies(w, r.URL.Query().Get("name"))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, assemblyTopologyAPI+"/"):
+		s.handleGetAssembly(w, strings.TrimPrefix(r.URL.Path, assemblyTopologyAPI+"/"))
+	case r.Method == http.MethodGet && r.URL.Path == processAPI:
+		s.handleFindProcesses(w, r.URL.Query().Get("assemblyName"), r.URL.Query().Get("limit"))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, processAPI+"/"):
+		s.handleGetProcess(w, strings.TrimPrefix(r.URL.Path, processAPI+"/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No handler for %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	client, clientSecret, ok := r.BasicAuth()
+	if r.Method != http.MethodPost || !ok || client != s.client || clientSecret != s.clientSecret {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+	token := fmt.Sprintf("token-%s", s.newID())
+	s.issuedTokens[token] = true
+	writeJSON(w, http.StatusOK, lm.AuthResponse{
+		AccessToken: token,
+		ExpiresIn:   s.tokenExpiresIn,
+		Scope:       "all",
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	return s.issuedTokens[strings.TrimPrefix(header, "Bearer ")]
+}
+
+func (s *Server) handleIntent(w http.ResponseWriter, intent string, body string) {
+	switch intent {
+	case "createAssembly":
+		request := lm.CreateAssemblyRequest{}
+		if !decode(w, body, &request) {
+			return
+		}
+		if s.findAssemblyByName(request.AssemblyName) != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Assembly with name %s already exists", request.AssemblyName))
+			return
+		}
+		assembly := &lm.Assembly{
+			ID:             s.newID(),
+			Name:           request.AssemblyName,
+			DescriptorName: request.DescriptorName,
+		}
+		s.assemblies[assembly.ID] = assembly
+		s.startProcess(w, CreateAssemblyIntent, assembly, func() {
+			assembly.State = request.IntendedState
+			assembly.Properties = toAssemblyProperties(request.Properties)
+		}, func() {
+			assembly.State = "Failed"
+		})
+	case "upgradeAssembly":
+		request := lm.UpgradeAssemblyRequest{}
+		if !decode(w, body, &request) {
+			return
+		}
+		assembly := s.requireAssembly(w, request.AssemblyName)
+		if assembly == nil {
+			return
+		}
+		s.startProcess(w, UpgradeAssemblyIntent, assembly, func() {
+			assembly.DescriptorName = request.DescriptorName
+			assembly.Properties = toAssemblyProperties(request.Properties)
+		}, nil)
+	case "changeAssemblyState":
+		request := lm.ChangeAssemblyStateRequest{}
+		if !decode(w, body, &request) {
+			return
+		}
+		assembly := s.requireAssembly(w, request.AssemblyName)
+		if assembly == nil {
+			return
+		}
+		s.startProcess(w, ChangeAssemblyStateIntent, assembly, func() {
+			assembly.State = request.IntendedState
+		}, nil)
+	case "deleteAssembly":
+		request := lm.DeleteAssemblyRequest{}
+		if !decode(w, body, &request) {
+			return
+		}
+		assembly := s.requireAssembly(w, request.AssemblyName)
+		if assembly == nil {
+			return
+		}
+		s.startProcess(w, DeleteAssemblyIntent, assembly, func() {
+			delete(s.assemblies, assembly.ID)
+		}, nil)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Unknown intent %s", intent))
+	}
+}
+
+func (s *Server) startProcess(w http.ResponseWriter, intentType string, assembly *lm.Assembly, onComplete func(), onFailure func()) {
+	lifecycle, ok := s.lifecycles[intentType]
+	if !ok {
+		lifecycle = DefaultProcessLifecycle
+	}
+	p := &process{
+		Process: lm.Process{
+			ID:         s.newID(),
+			AssemblyID: assembly.ID,
+			IntentType: intentType,
+		},
+		assemblyName: assembly.Name,
+		lifecycle:    lifecycle,
+	}
+	p.complete = func() {
+		switch p.Status {
+		case "Completed":
+			onComplete()
+		case "Failed":
+			if onFailure != nil {
+				onFailure()
+			}
+		}
+	}
+	p.Status = p.statusAt(0)
+	if p.step >= len(lifecycle.Steps) {
+		p.finish()
+	}
+	s.processes[p.ID] = p
+	s.processOrder = append(s.processOrder, p.ID)
+	w.Header().Set("Location", fmt.Sprintf("%s%s/%s", s.URL, processAPI, p.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (p *process) statusAt(step int) string {
+	if step < len(p.lifecycle.Steps) {
+		return p.lifecycle.Steps[step]
+	}
+	return p.lifecycle.FinalStatus
+}
+
+func (p *process) finish() {
+	p.Status = p.lifecycle.FinalStatus
+	p.StatusReason = p.lifecycle.StatusReason
+	p.complete()
+}
+
+// observe returns the current view of the process and moves it on to the next step of its lifecycle
+func (p *process) observe() lm.Process {
+	observed := p.Process
+	if p.step < len(p.lifecycle.Steps) {
+		p.step++
+		if p.step >= len(p.lifecycle.Steps) {
+			p.finish()
+		} else {
+			p.Status = p.statusAt(p.step)
+		}
+	}
+	return observed
+}
+
+func (s *Server) handleFindAssemblies(w http.ResponseWriter, assemblyName string) {
+	result := make([]lm.Assembly, 0)
+	for _, assembly := range s.assemblies {
+		if assemblyName == "" || assembly.Name == assemblyName {
+			result = append(result, copyAssembly(assembly))
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGetAssembly(w http.ResponseWriter, assemblyID string) {
+	assembly, ok := s.assemblies[assemblyID]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Assembly %s not found", assemblyID))
+		return
+	}
+	writeJSON(w, http.StatusOK, copyAssembly(assembly))
+}
+
+func (s *Server) handleFindProcesses(w http.ResponseWriter, assemblyName string, limit string) {
+	max := len(s.processOrder)
+	if limit != "" {
+		if parsed, err := strconv.Atoi(limit); err == nil {
+			max = parsed
+		}
+	}
+	result := make([]lm.Process, 0)
+	// Newest first
+	for i := len(s.processOrder) - 1; i >= 0 && len(result) < max; i-- {
+		p := s.processes[s.processOrder[i]]
+		if assemblyName == "" || p.assemblyName == assemblyName {
+			result = append(result, p.Process)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGetProcess(w http.ResponseWriter, processID string) {
+	p, ok := s.processes[processID]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Process %s not found", processID))
+		return
+	}
+	writeJSON(w, http.StatusOK, p.observe())
+}
+
+func (s *Server) requireAssembly(w http.ResponseWriter, assemblyName string) *lm.Assembly {
+	assembly := s.findAssemblyByName(assemblyName)
+	if assembly == nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Assembly with name %s not found", assemblyName))
+	}
+	return assembly
+}
+
+func (s *Server) findAssemblyByName(assemblyName string) *lm.Assembly {
+	for _, assembly := range s.assemblies {
+		if assembly.Name == assemblyName {
+			return assembly
+		}
+	}
+	return nil
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("%08d-0000-0000-0000-000000000000", s.nextID)
+}
+
+func copyAssembly(assembly *lm.Assembly) lm.Assembly {
+	copied := *assembly
+	copied.Properties = append([]lm.AssemblyProperty(nil), assembly.Properties...)
+	return copied
+}
+
+func toAssemblyProperties(properties map[string]string) []lm.AssemblyProperty {
+	result := make([]lm.AssemblyProperty, 0, len(properties))
+	for name, value := range properties {
+		result = append(result, lm.AssemblyProperty{Name: name, Value: value})
+	}
+	return result
+}
+
+func decode(w http.ResponseWriter, body string, into interface{}) bool {
+	if err := json.Unmarshal([]byte(body), into); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %s", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"localizedMessage": message})
+}
@@ -0,0 +1,212 @@
+package fake
+
+import (
+	"net/http"
+	"testing"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+)
+
+func newTestClient(t *testing.T, server *Server) *lm.LMRestClient {
+	t.Helper()
+	client, err := lm.BuildClient(server.Configuration())
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	return client
+}
+
+func createAssembly(t *testing.T, client *lm.LMRestClient, name string) string {
+	t.Helper()
+	processID, err := client.CreateAssembly(lm.CreateAssemblyRequest{
+		AssemblyName:   name,
+		DescriptorName: "assembly::test::1.0",
+		IntendedState:  "Active",
+		Properties:     map[string]string{"size": "small"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Assembly: %s", err)
+	}
+	return processID
+}
+
+func TestProcessLifecycle(t *testing.T) {
+	tests := []struct {
+		name          string
+		lifecycle     *ProcessLifecycle
+		statuses      []string
+		assemblyState string
+	}{
+		{
+			name:          "default",
+			statuses:      []string{"Pending", "In Progress", "Completed", "Completed"},
+			assemblyState: "Active",
+		},
+		{
+			name:          "no steps",
+			lifecycle:     &ProcessLifecycle{},
+			statuses:      []string{"Completed"},
+			assemblyState: "Active",
+		},
+		{
+			name:          "failed",
+			lifecycle:     &ProcessLifecycle{Steps: []string{"In Progress"}, FinalStatus: "Failed", StatusReason: "boom"},
+			statuses:      []string{"In Progress", "Failed", "Failed"},
+			assemblyState: "Failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer()
+			defer server.Close()
+			if tt.lifecycle != nil {
+				server.SetProcessLifecycle(CreateAssemblyIntent, *tt.lifecycle)
+			}
+			client := newTestClient(t, server)
+			processID := createAssembly(t, client, "a1")
+			for i, expected := range tt.statuses {
+				process, found, err := client.GetProcessByID(processID)
+				if err != nil || !found {
+					t.Fatalf("Failed to get process: found=%t err=%v", found, err)
+				}
+				if process.Status != expected {
+					t.Errorf("Retrieval %d: expected status %s but was %s", i+1, expected, process.Status)
+				}
+				if process.IntentType != CreateAssemblyIntent {
+					t.Errorf("Expected intent type %s but was %s", CreateAssemblyIntent, process.IntentType)
+				}
+			}
+			assembly, found := server.GetAssembly("a1")
+			if !found {
+				t.Fatalf("Assembly not found")
+			}
+			if assembly.State != tt.assemblyState {
+				t.Errorf("Expected Assembly state %s but was %s", tt.assemblyState, assembly.State)
+			}
+		})
+	}
+}
+
+func TestListingProcessesDoesNotAdvanceThem(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := newTestClient(t, server)
+	processID := createAssembly(t, client, "a1")
+
+	for i := 0; i < 3; i++ {
+		process, found, err := client.GetLatestProcess("a1")
+		if err != nil || !found {
+			t.Fatalf("Failed to get latest process: found=%t err=%v", found, err)
+		}
+		if process.ID != processID || process.Status != "Pending" {
+			t.Fatalf("Expected process %s to still be Pending but was %s %s", processID, process.ID, process.Status)
+		}
+	}
+
+	server.Tick()
+	server.Tick()
+	process, _, err := client.GetLatestProcess("a1")
+	if err != nil {
+		t.Fatalf("Failed to get latest process: %s", err)
+	}
+	if process.Status != "Completed" {
+		t.Errorf("Expected process to be Completed after two ticks but was %s", process.Status)
+	}
+	if assembly, _ := server.GetAssembly("a1"); assembly.State != "Active" {
+		t.Errorf("Expected Assembly to be Active but was %s", assembly.State)
+	}
+}
+
+func TestIntents(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetProcessLifecycle(CreateAssemblyIntent, ProcessLifecycle{})
+	server.SetProcessLifecycle(UpgradeAssemblyIntent, ProcessLifecycle{})
+	server.SetProcessLifecycle(ChangeAssemblyStateIntent, ProcessLifecycle{})
+	server.SetProcessLifecycle(DeleteAssemblyIntent, ProcessLifecycle{})
+	client := newTestClient(t, server)
+	createAssembly(t, client, "a1")
+
+	if _, err := client.CreateAssembly(lm.CreateAssemblyRequest{AssemblyName: "a1", DescriptorName: "assembly::test::1.0"}); err == nil {
+		t.Errorf("Expected creating a second Assembly with the same name to fail")
+	}
+	if _, err := client.UpgradeAssembly(lm.UpgradeAssemblyRequest{AssemblyName: "a1", DescriptorName: "assembly::test::2.0", Properties: map[string]string{"size": "large"}}); err != nil {
+		t.Fatalf("Failed to upgrade Assembly: %s", err)
+	}
+	if _, err := client.ChangeAssemblyState(lm.ChangeAssemblyStateRequest{AssemblyName: "a1", IntendedState: "Inactive"}); err != nil {
+		t.Fatalf("Failed to change Assembly state: %s", err)
+	}
+	assembly, found, err := client.GetAssemblyByName("a1")
+	if err != nil || !found {
+		t.Fatalf("Failed to get Assembly: found=%t err=%v", found, err)
+	}
+	if assembly.DescriptorName != "assembly::test::2.0" || assembly.State != "Inactive" {
+		t.Errorf("Expected Assembly to be Inactive with descriptor assembly::test::2.0 but was %s with %s", assembly.State, assembly.DescriptorName)
+	}
+	if len(assembly.Properties) != 1 || assembly.Properties[0].Value != "large" {
+		t.Errorf("Expected property size=large but was %v", assembly.Properties)
+	}
+	if byID, found, _ := client.GetAssemblyByID(assembly.ID); !found || byID.Name != "a1" {
+		t.Errorf("Expected to find Assembly a1 by ID %s", assembly.ID)
+	}
+
+	if _, err := client.DeleteAssembly(lm.DeleteAssemblyRequest{AssemblyName: "a1"}); err != nil {
+		t.Fatalf("Failed to delete Assembly: %s", err)
+	}
+	if _, found, _ := client.GetAssemblyByName("a1"); found {
+		t.Errorf("Expected Assembly to have been deleted")
+	}
+	if _, err := client.ChangeAssemblyState(lm.ChangeAssemblyStateRequest{AssemblyName: "a1", IntendedState: "Active"}); err == nil {
+		t.Errorf("Expected an intent on a deleted Assembly to fail")
+	}
+	if requests := server.IntentRequests("createAssembly"); len(requests) != 2 {
+		t.Errorf("Expected 2 createAssembly requests but there were %d", len(requests))
+	}
+}
+
+func TestInjectError(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := newTestClient(t, server)
+	server.InjectError(http.MethodPost, "/api/intent/createAssembly", http.StatusInternalServerError, 1)
+
+	if _, err := client.CreateAssembly(lm.CreateAssemblyRequest{AssemblyName: "a1", DescriptorName: "assembly::test::1.0"}); err == nil {
+		t.Fatalf("Expected the injected error to fail the request")
+	}
+	if _, found := server.GetAssembly("a1"); found {
+		t.Errorf("Expected no Assembly to be created by a failed request")
+	}
+	createAssembly(t, client, "a1")
+}
+
+func TestSecurity(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.EnableSecurity("client", "secret", 3600)
+
+	resp, err := http.Get(server.URL + "/api/topology/assemblies")
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a request without a token to be rejected with 401 but was %d", resp.StatusCode)
+	}
+
+	client := newTestClient(t, server)
+	defer client.Close()
+	if _, _, err := client.GetAssemblyByName("a1"); err != nil {
+		t.Errorf("Expected a request with a token to be accepted: %s", err)
+	}
+
+	configuration := server.Configuration()
+	configuration.ClientSecret = "wrong"
+	badClient, err := lm.BuildClient(configuration)
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	defer badClient.Close()
+	if _, _, err := badClient.GetAssemblyByName("a1"); err == nil {
+		t.Errorf("Expected a client with the wrong credentials to be rejected")
+	}
+}
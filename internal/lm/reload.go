@@ -0,0 +1,151 @@
+package lm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var reloadLog = logf.Log.WithName("lm_reload")
+
+const defaultReloadInterval = 10 * time.Second
+
+// ReloadingLMClient is an LMClient which watches the configuration file and client secret file for changes,
+// rebuilding the underlying LMRestClient (and its LMSecurityCtrl) in place so credentials can be rotated without a restart
+type ReloadingLMClient struct {
+	configurationPath string
+	interval          time.Duration
+
+	mu               sync.RWMutex
+	client           *LMRestClient
+	clientSecretPath string
+	observed         [][]byte
+}
+
+// blank assignment to verify that ReloadingLMClient implements LMClient
+var _ LMClient = &ReloadingLMClient{}
+
+// BuildReloadingClient reads the configuration at configurationPath and builds a client from it.
+// Call Start (or add the client to a Manager) to begin watching for changes.
+func BuildReloadingClient(configurationPath string) (*ReloadingLMClient, error) {
+	reloadingClient := &ReloadingLMClient{
+		configurationPath: configurationPath,
+		interval:          defaultReloadInterval,
+	}
+	if err := reloadingClient.reload(); err != nil {
+		return nil, err
+	}
+	return reloadingClient, nil
+}
+
+// Start polls for changes to the configuration until the stop channel is closed
+func (c *ReloadingLMClient) Start(stop <-chan struct{}) error {
+	reloadLog.Info("Watching LM configuration for changes", "path", c.configurationPath, "interval", c.interval.String())
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if c.hasChanged() {
+				reloadLog.Info("LM configuration changed, rebuilding LM client", "path", c.configurationPath)
+				if err := c.reload(); err != nil {
+					reloadLog.Error(err, "Failed to rebuild LM client, continuing with previous configuration")
+				}
+			}
+		}
+	}
+}
+
+// NeedLeaderElection allows configuration to be reloaded on all replicas, not just the leader
+func (c *ReloadingLMClient) NeedLeaderElection() bool {
+	return false
+}
+
+func (c *ReloadingLMClient) readWatchedFiles(clientSecretPath string) [][]byte {
+	contents := make([][]byte, 0, 2)
+	for _, path := range []string{c.configurationPath, clientSecretPath} {
+		// A missing file is observed as empty content
+		content, _ := ioutil.ReadFile(path)
+		contents = append(contents, content)
+	}
+	return contents
+}
+
+func (c *ReloadingLMClient) hasChanged() bool {
+	c.mu.RLock()
+	clientSecretPath := c.clientSecretPath
+	observed := c.observed
+	c.mu.RUnlock()
+	current := c.readWatchedFiles(clientSecretPath)
+	for i := range current {
+		if !bytes.Equal(current[i], observed[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ReloadingLMClient) reload() error {
+	configuration, err := ReadLMConfigurationFrom(c.configurationPath)
+	if err != nil {
+		return err
+	}
+	observed := c.readWatchedFiles(configuration.ClientSecretFile)
+	client, err := BuildClient(configuration)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		// Requests already using the previous client can still complete, only its background token refresh is stopped
+		c.client.Close()
+	}
+	c.client = client
+	c.clientSecretPath = configuration.ClientSecretFile
+	c.observed = observed
+	return nil
+}
+
+func (c *ReloadingLMClient) current() *LMRestClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+func (c *ReloadingLMClient) CreateAssembly(createRequest CreateAssemblyRequest) (processID string, err error) {
+	return c.current().CreateAssembly(createRequest)
+}
+
+func (c *ReloadingLMClient) UpgradeAssembly(upgradeRequest UpgradeAssemblyRequest) (processID string, err error) {
+	return c.current().UpgradeAssembly(upgradeRequest)
+}
+
+func (c *ReloadingLMClient) ChangeAssemblyState(changeStateRequest ChangeAssemblyStateRequest) (processID string, err error) {
+	return c.current().ChangeAssemblyState(changeStateRequest)
+}
+
+func (c *ReloadingLMClient) DeleteAssembly(deleteRequest DeleteAssemblyRequest) (processID string, err error) {
+	return c.current().DeleteAssembly(deleteRequest)
+}
+
+func (c *ReloadingLMClient) GetAssemblyByID(assemblyID string) (*Assembly, bool, error) {
+	return c.current().GetAssemblyByID(assemblyID)
+}
+
+func (c *ReloadingLMClient) GetAssemblyByName(assemblyName string) (*Assembly, bool, error) {
+	return c.current().GetAssemblyByName(assemblyName)
+}
+
+func (c *ReloadingLMClient) GetLatestProcess(assemblyName string) (*Process, bool, error) {
+	return c.current().GetLatestProcess(assemblyName)
+}
+
+func (c *ReloadingLMClient) GetProcessByID(processID string) (*Process, bool, error) {
+	return c.current().GetProcessByID(processID)
+}
@@ -0,0 +1,78 @@
+package lm_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %s", path, err)
+	}
+}
+
+// eventually retries the given check until it succeeds or the timeout passes, returning the last error
+func eventually(timeout time.Duration, check func() error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadingClientRotatesClientSecret(t *testing.T) {
+	server := newSecureServer(3600)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "lm-reload")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	configurationPath := filepath.Join(dir, "config.yaml")
+	clientSecretPath := filepath.Join(dir, "clientSecret")
+	configuration := fmt.Sprintf("base: %s\nclient: client\nsecure: true\nclientSecretFile: %s\n", server.URL, clientSecretPath)
+	writeFile(t, configurationPath, configuration)
+	writeFile(t, clientSecretPath, "secret\n")
+
+	client, err := lm.BuildReloadingClient(configurationPath)
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	lm.SetReloadInterval(client, 10*time.Millisecond)
+	stop := make(chan struct{})
+	defer close(stop)
+	go client.Start(stop)
+	if _, _, err := client.GetAssemblyByName("a1"); err != nil {
+		t.Fatalf("Expected the request to succeed with the initial client secret: %s", err)
+	}
+
+	// Rotated in LM and then in the mounted Secret, so the token issued for the previous secret is no longer accepted
+	server.EnableSecurity("client", "rotated", 3600)
+	server.RevokeTokens()
+	writeFile(t, clientSecretPath, "rotated\n")
+	if err := eventually(5*time.Second, func() error {
+		_, _, err := client.GetAssemblyByName("a1")
+		return err
+	}); err != nil {
+		t.Fatalf("Expected the rotated client secret to be used: %s", err)
+	}
+
+	// A broken configuration is not used, leaving the previous client in place
+	writeFile(t, configurationPath, "base: [\n")
+	time.Sleep(100 * time.Millisecond)
+	server.RevokeTokens()
+	if _, _, err := client.GetAssemblyByName("a1"); err != nil {
+		t.Errorf("Expected the previous client to keep working once the configuration is broken: %s", err)
+	}
+
+}
@@ -0,0 +1,161 @@
+package lm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v3"
+	resty "github.com/go-resty/resty/v2"
+)
+
+// RetryConfiguration controls how requests to LM are retried after transient failures (502, 503, 504 and 429 responses,
+// connection resets, refused connections and timeouts)
+type RetryConfiguration struct {
+	// Maximum number of attempts made for a single request, including the first (1 disables retries)
+	MaxAttempts int `yaml:"maxAttempts"`
+	// Delay before the first retry, doubled on each subsequent retry
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	// Upper limit on the delay between retries. A Retry-After longer than this ends the retries
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	// Randomization factor (0 to 1) applied to each delay, to avoid many clients retrying in step
+	Jitter float64 `yaml:"jitter"`
+	// Also retry intent requests (e.g. createAssembly). Off by default as a request which reached LM before
+	// failing may have already started a process, in which case a retry would start a second one
+	RetryIntents bool `yaml:"retryIntents"`
+}
+
+// DefaultRetryConfiguration returns the retry policy used when none is configured
+func DefaultRetryConfiguration() RetryConfiguration {
+	return RetryConfiguration{
+		MaxAttempts:  3,
+		BaseDelay:    500 * time.Millisecond,
+		MaxDelay:     10 * time.Second,
+		Jitter:       0.5,
+		RetryIntents: false,
+	}
+}
+
+// transientStatusError marks a response with a status code which is worth retrying
+type transientStatusError struct {
+	statusCode int
+}
+
+func (e *transientStatusError) Error() string {
+	return fmt.Sprintf("LM returned transient status %d", e.statusCode)
+}
+
+// retryAfterBackOff uses the delay requested by LM through a Retry-After header in place of the delegate's delay, when one was given
+type retryAfterBackOff struct {
+	delegate   backoff.BackOff
+	maxDelay   time.Duration
+	retryAfter time.Duration
+}
+
+func (b *retryAfterBackOff) NextBackOff() time.Duration {
+	next := b.delegate.NextBackOff()
+	if next == backoff.Stop || b.retryAfter <= 0 {
+		return next
+	}
+	retryAfter := b.retryAfter
+	b.retryAfter = 0
+	if retryAfter > b.maxDelay {
+		return backoff.Stop
+	}
+	return retryAfter
+}
+
+func (b *retryAfterBackOff) Reset() {
+	b.retryAfter = 0
+	b.delegate.Reset()
+}
+
+func (retry RetryConfiguration) newBackOff() *retryAfterBackOff {
+	exponential := backoff.NewExponentialBackOff()
+	exponential.InitialInterval = retry.BaseDelay
+	exponential.MaxInterval = retry.MaxDelay
+	exponential.RandomizationFactor = retry.Jitter
+	exponential.Multiplier = 2
+	exponential.MaxElapsedTime = 0
+	exponential.Reset()
+	return &retryAfterBackOff{
+		delegate: backoff.WithMaxRetries(exponential, uint64(retry.MaxAttempts-1)),
+		maxDelay: retry.MaxDelay,
+	}
+}
+
+// executeWithRetry runs attempt until it succeeds, fails with an error which is not transient or the retry policy is exhausted.
+// When the final attempt fails with a transient status code the response is returned without an error, so the caller reports it as usual
+func (retry RetryConfiguration) executeWithRetry(method string, url string, attempt func() (*resty.Response, error)) (*resty.Response, error) {
+	if retry.MaxAttempts <= 1 || (method != resty.MethodGet && !retry.RetryIntents) {
+		return attempt()
+	}
+	var resp *resty.Response
+	policy := retry.newBackOff()
+	operation := func() error {
+		var err error
+		resp, err = attempt()
+		if err != nil {
+			if isTransientError(err) {
+				return err
+			}
+			return backoff.Permanent(err)
+		}
+		if isTransientStatus(resp.StatusCode()) {
+			policy.retryAfter = parseRetryAfter(resp)
+			return &transientStatusError{statusCode: resp.StatusCode()}
+		}
+		return nil
+	}
+	notify := func(err error, delay time.Duration) {
+		clientLog.Info("Request to LM failed with a transient error, will retry", LogKeys.URL, url, "error", err.Error(), "retryInMillis", delay.Milliseconds())
+	}
+	err := backoff.RetryNotify(operation, policy, notify)
+	if _, ok := err.(*transientStatusError); ok {
+		return resp, nil
+	}
+	return resp, err
+}
+
+func isTransientStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+func isTransientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter reads the Retry-After header of 429 and 503 responses, given either in seconds or as an HTTP date
+func parseRetryAfter(resp *resty.Response) time.Duration {
+	if resp.StatusCode() != http.StatusTooManyRequests && resp.StatusCode() != http.StatusServiceUnavailable {
+		return 0
+	}
+	value := resp.Header().Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
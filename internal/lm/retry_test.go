@@ -0,0 +1,149 @@
+package lm_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+)
+
+var testRetryConfiguration = lm.RetryConfiguration{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Jitter:      0,
+}
+
+func newRetryingClient(t *testing.T, server *fake.Server, retry lm.RetryConfiguration) *lm.LMRestClient {
+	t.Helper()
+	configuration := server.Configuration()
+	configuration.Retry = retry
+	client, err := lm.BuildClient(configuration)
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	return client
+}
+
+func requestsTo(server *fake.Server, method string, path string) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if request.Method == method && request.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func TestTransientFailuresAreRetried(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		failures   int
+		expectErr  bool
+		attempts   int
+	}{
+		{name: "recovers before attempts run out", statusCode: http.StatusServiceUnavailable, failures: 2, attempts: 3},
+		{name: "attempts run out", statusCode: http.StatusBadGateway, failures: 3, expectErr: true, attempts: 3},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, failures: 1, attempts: 2},
+		{name: "not transient", statusCode: http.StatusInternalServerError, failures: 1, expectErr: true, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+			client := newRetryingClient(t, server, testRetryConfiguration)
+			server.InjectError(http.MethodGet, "/api/topology/assemblies", tt.statusCode, tt.failures)
+
+			_, _, err := client.GetAssemblyByName("a1")
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected the request to fail")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("Expected the request to succeed: %s", err)
+			}
+			if attempts := requestsTo(server, http.MethodGet, "/api/topology/assemblies"); attempts != tt.attempts {
+				t.Errorf("Expected %d attempts but there were %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestOnlyGetsAreRetried(t *testing.T) {
+	tests := []struct {
+		name         string
+		retryIntents bool
+		expectErr    bool
+		attempts     int
+	}{
+		{name: "intents not retried by default", expectErr: true, attempts: 1},
+		{name: "intents retried when enabled", retryIntents: true, attempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+			retry := testRetryConfiguration
+			retry.RetryIntents = tt.retryIntents
+			client := newRetryingClient(t, server, retry)
+			server.InjectError(http.MethodPost, "/api/intent/createAssembly", http.StatusServiceUnavailable, 1)
+
+			_, err := client.CreateAssembly(lm.CreateAssemblyRequest{AssemblyName: "a1", DescriptorName: "assembly::test::1.0", IntendedState: "Active"})
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected the intent to fail")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("Expected the intent to succeed: %s", err)
+			}
+			if attempts := len(server.IntentRequests("createAssembly")); attempts != tt.attempts {
+				t.Errorf("Expected %d attempts but there were %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		// Given in place of retryAfter, as an HTTP date this far from the time of the request
+		retryAfterDate time.Duration
+		expectErr      bool
+		attempts       int
+		minElapsed     time.Duration
+		maxElapsed     time.Duration
+	}{
+		{name: "seconds", statusCode: http.StatusServiceUnavailable, retryAfter: "1", attempts: 2, minElapsed: time.Second, maxElapsed: 1900 * time.Millisecond},
+		{name: "HTTP date", statusCode: http.StatusTooManyRequests, retryAfterDate: 2 * time.Second, attempts: 2, minElapsed: 500 * time.Millisecond, maxElapsed: 2500 * time.Millisecond},
+		{name: "longer than max delay", statusCode: http.StatusServiceUnavailable, retryAfter: "30", expectErr: true, attempts: 1, maxElapsed: 500 * time.Millisecond},
+		{name: "ignored on other status codes", statusCode: http.StatusBadGateway, retryAfter: "30", attempts: 2, maxElapsed: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+			client := newRetryingClient(t, server, testRetryConfiguration)
+			retryAfter := tt.retryAfter
+			if tt.retryAfterDate > 0 {
+				retryAfter = time.Now().Add(tt.retryAfterDate).UTC().Format(http.TimeFormat)
+			}
+			server.InjectRetryAfter(http.MethodGet, "/api/topology/assemblies", tt.statusCode, retryAfter, 1)
+
+			start := time.Now()
+			_, _, err := client.GetAssemblyByName("a1")
+			elapsed := time.Since(start)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected the request to fail")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("Expected the request to succeed: %s", err)
+			}
+			if attempts := requestsTo(server, http.MethodGet, "/api/topology/assemblies"); attempts != tt.attempts {
+				t.Errorf("Expected %d attempts but there were %d", tt.attempts, attempts)
+			}
+			if elapsed < tt.minElapsed || elapsed > tt.maxElapsed {
+				t.Errorf("Expected the request to take between %s and %s but took %s", tt.minElapsed, tt.maxElapsed, elapsed)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	resty "github.com/go-resty/resty/v2"
@@ -13,6 +14,12 @@ import (
 
 const oauthApi = "/oauth/token"
 
+// defaultTokenRefreshRatio is the fraction of a token's lifetime after which it is proactively refreshed
+const defaultTokenRefreshRatio = 0.8
+
+// minTokenRefreshDelay prevents a tight refresh loop when LM returns tokens which are about to expire
+const minTokenRefreshDelay = 1 * time.Second
+
 var securityLog = logf.Log.WithName("lm_security")
 
 type AuthResponse struct {
@@ -21,17 +28,31 @@ type AuthResponse struct {
 	Scope       string `json:"scope"`
 }
 
+// tokenRequest is a single in-flight request for a new access token, shared by all callers waiting on it
+type tokenRequest struct {
+	done        chan struct{}
+	accessToken string
+	err         error
+}
+
+// LMSecurityCtrl obtains access tokens from LM. It is safe for concurrent use: callers share the current token,
+// and when a new one is needed a single request is made on behalf of all of them.
 type LMSecurityCtrl struct {
 	restClient      *resty.Client
 	lmConfiguration *LMConfiguration
-	auth            *AuthResponse
-	authTime        time.Time
+
+	mu           sync.Mutex
+	auth         *AuthResponse
+	authTime     time.Time
+	inflight     *tokenRequest
+	refreshTimer *time.Timer
+	stopped      bool
 }
 
-func BuildCtrl(lmConfiguration *LMConfiguration) *LMSecurityCtrl {
+func BuildCtrl(lmConfiguration *LMConfiguration, tlsConfig *tls.Config) *LMSecurityCtrl {
 	securityLog.Info("Building LM security ctrl", LogKeys.URL, lmConfiguration.Base, LogKeys.Client, lmConfiguration.Client)
 	restClient := resty.New()
-	restClient.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	restClient.SetTLSClientConfig(tlsConfig)
 	restClient.SetTimeout(2 * time.Minute)
 	lmSecurityCtrl := LMSecurityCtrl{
 		restClient:      restClient,
@@ -44,16 +65,128 @@ func (ctrl *LMSecurityCtrl) getAccessToken() (string, error) {
 	if !ctrl.lmConfiguration.Secure {
 		return "", nil
 	}
-	if ctrl.needNewToken() {
+	ctrl.mu.Lock()
+	if !ctrl.needNewToken() {
+		accessToken := ctrl.auth.AccessToken
+		ctrl.mu.Unlock()
+		return accessToken, nil
+	}
+	request := ctrl.startTokenRequest()
+	ctrl.mu.Unlock()
+
+	<-request.done
+	return request.accessToken, request.err
+}
+
+// invalidateToken discards the given access token, after it has been rejected by LM, so the next call to getAccessToken requests a new one.
+// Tokens which have already been replaced are ignored, so many callers rejected with the same token still result in a single new request
+func (ctrl *LMSecurityCtrl) invalidateToken(accessToken string) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	if ctrl.auth != nil && ctrl.auth.AccessToken == accessToken {
+		securityLog.Info("Access token rejected by LM, discarding it")
+		ctrl.auth = nil
+	}
+}
+
+// stop cancels any scheduled background refresh. The ctrl can still be used but will only request tokens on demand
+func (ctrl *LMSecurityCtrl) stop() {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.stopped = true
+	if ctrl.refreshTimer != nil {
+		ctrl.refreshTimer.Stop()
+		ctrl.refreshTimer = nil
+	}
+}
+
+// startTokenRequest returns the in-flight token request, starting one if none exists. Must be called with mu held
+func (ctrl *LMSecurityCtrl) startTokenRequest() *tokenRequest {
+	if ctrl.inflight != nil {
+		return ctrl.inflight
+	}
+	request := &tokenRequest{done: make(chan struct{})}
+	ctrl.inflight = request
+	go func() {
 		result, err := ctrl.requestAccessToken()
+		ctrl.mu.Lock()
 		if err != nil {
-			return "", err
+			securityLog.Error(err, "Failed to obtain access token")
+			request.err = err
+			ctrl.scheduleRetry()
+		} else {
+			previous := ctrl.auth
+			ctrl.auth = result
+			ctrl.authTime = time.Now()
+			request.accessToken = result.AccessToken
+			ctrl.scheduleRefresh(previous)
 		}
-		ctrl.auth = result
-		ctrl.authTime = time.Now()
+		ctrl.inflight = nil
+		ctrl.mu.Unlock()
+		close(request.done)
+	}()
+	return request
+}
+
+// scheduleRefresh arranges for the current token to be replaced in the background before it expires. Must be called with mu held
+func (ctrl *LMSecurityCtrl) scheduleRefresh(previous *AuthResponse) {
+	if ctrl.refreshTimer != nil {
+		ctrl.refreshTimer.Stop()
+		ctrl.refreshTimer = nil
+	}
+	if ctrl.stopped {
+		return
+	}
+	ratio := ctrl.lmConfiguration.TokenRefreshRatio
+	if ratio <= 0 || ratio >= 1 {
+		ratio = defaultTokenRefreshRatio
+	}
+	lifetime := time.Duration(ctrl.auth.ExpiresIn) * time.Second
+	delay := time.Duration(float64(lifetime) * ratio)
+	if previous != nil && previous.AccessToken == ctrl.auth.AccessToken {
+		// LM handed back the token we already had, a new one will only be issued once it has expired
+		delay = lifetime + minTokenRefreshDelay
+	}
+	if delay < minTokenRefreshDelay {
+		delay = minTokenRefreshDelay
+	}
+	securityLog.Info("Scheduling background refresh of access token", LogKeys.ExpirationSecs, ctrl.auth.ExpiresIn, "refreshInSecs", int(delay.Seconds()))
+	ctrl.startRefreshTimer(delay)
+}
+
+// scheduleRetry arranges for a failed request for a new token to be made again in the background, whilst the current token is still valid.
+// Once it has expired a new token is requested on demand instead. Must be called with mu held
+func (ctrl *LMSecurityCtrl) scheduleRetry() {
+	if ctrl.stopped || ctrl.auth == nil {
+		return
+	}
+	remaining := ctrl.authTime.Add(time.Duration(ctrl.auth.ExpiresIn) * time.Second).Sub(time.Now())
+	if remaining <= 0 {
+		return
 	}
+	// Retried at half the remaining lifetime, so there are several attempts before the token expires
+	delay := remaining / 2
+	if delay < minTokenRefreshDelay {
+		delay = minTokenRefreshDelay
+	}
+	securityLog.Info("Scheduling retry of background refresh of access token", "retryInSecs", int(delay.Seconds()))
+	ctrl.startRefreshTimer(delay)
+}
 
-	return ctrl.auth.AccessToken, nil
+// startRefreshTimer requests a new token in the background after the delay, replacing any refresh already scheduled. Must be called with mu held
+func (ctrl *LMSecurityCtrl) startRefreshTimer(delay time.Duration) {
+	if ctrl.refreshTimer != nil {
+		ctrl.refreshTimer.Stop()
+	}
+	ctrl.refreshTimer = time.AfterFunc(delay, func() {
+		ctrl.mu.Lock()
+		if ctrl.stopped {
+			ctrl.mu.Unlock()
+			return
+		}
+		ctrl.startTokenRequest()
+		ctrl.mu.Unlock()
+	})
 }
 
 func (ctrl *LMSecurityCtrl) requestAccessToken() (*AuthResponse, error) {
@@ -87,6 +220,7 @@ func (ctrl *LMSecurityCtrl) requestAccessToken() (*AuthResponse, error) {
 	}
 }
 
+// needNewToken reports whether the current token is missing or expired. Must be called with mu held
 func (ctrl *LMSecurityCtrl) needNewToken() bool {
 	if ctrl.auth == nil {
 		securityLog.Info("No existing access token, must request one")
@@ -95,18 +229,11 @@ func (ctrl *LMSecurityCtrl) needNewToken() bool {
 
 	expirationSeconds := ctrl.auth.ExpiresIn
 	authenticatedSeconds := time.Now().Sub(ctrl.authTime).Seconds()
-	securityLog.Info("Checking if existing access token has expired", LogKeys.AuthTime, ctrl.authTime, LogKeys.ExpirationSecs, ctrl.auth.ExpiresIn, LogKeys.AuthenticatedSecs, authenticatedSeconds)
 
 	if int(authenticatedSeconds) >= int(expirationSeconds) {
 		securityLog.Info("Token expired, must request a new one", LogKeys.AuthTime, ctrl.authTime, LogKeys.ExpirationSecs, ctrl.auth.ExpiresIn, LogKeys.AuthenticatedSecs, authenticatedSeconds)
 		return true
 	}
-	// If the token expires within 1 second, wait and get a new one
-	if int32(authenticatedSeconds) >= (expirationSeconds - 1) {
-		securityLog.Info("Token expires in less than 1 second, waiting before requesting a new Token", LogKeys.AuthTime, ctrl.authTime, LogKeys.ExpirationSecs, ctrl.auth.ExpiresIn, LogKeys.AuthenticatedSecs, authenticatedSeconds)
-		time.Sleep(2 * time.Second)
-		return true
-	}
 
 	return false
 }
@@ -0,0 +1,140 @@
+package lm_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+)
+
+func newSecureServer(tokenExpiresIn int32) *fake.Server {
+	server := fake.NewServer()
+	server.EnableSecurity("client", "secret", tokenExpiresIn)
+	return server
+}
+
+func tokenRequests(server *fake.Server) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if request.Path == "/oauth/token" {
+			count++
+		}
+	}
+	return count
+}
+
+// getConcurrently makes the given number of requests to LM at the same time, failing the test if any of them fail
+func getConcurrently(t *testing.T, client *lm.LMRestClient, count int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := client.GetAssemblyByName("a1"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Request failed: %s", err)
+	}
+}
+
+func TestConcurrentRequestsShareToken(t *testing.T) {
+	server := newSecureServer(3600)
+	defer server.Close()
+	client, err := lm.BuildClient(server.Configuration())
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	defer client.Close()
+
+	getConcurrently(t, client, 20)
+	if count := tokenRequests(server); count != 1 {
+		t.Errorf("Expected a single token request shared by all requests but there were %d", count)
+	}
+}
+
+func TestRejectedTokenIsReplacedOnce(t *testing.T) {
+	server := newSecureServer(3600)
+	defer server.Close()
+	client, err := lm.BuildClient(server.Configuration())
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	defer client.Close()
+	getConcurrently(t, client, 1)
+
+	// Every request is rejected with the same token, then retried with a single new one
+	server.RevokeTokens()
+	getConcurrently(t, client, 20)
+	if count := tokenRequests(server); count != 2 {
+		t.Errorf("Expected a single new token request after the token was rejected but there were %d token requests", count)
+	}
+	unauthorized := 0
+	for _, request := range server.Requests() {
+		if request.Path == "/api/topology/assemblies" {
+			unauthorized++
+		}
+	}
+	if unauthorized != 41 {
+		t.Errorf("Expected each rejected request to be sent once more, 41 requests in total, but there were %d", unauthorized)
+	}
+}
+
+func TestBackgroundTokenRefresh(t *testing.T) {
+	server := newSecureServer(2)
+	defer server.Close()
+	configuration := server.Configuration()
+	configuration.TokenRefreshRatio = 0.5
+	client, err := lm.BuildClient(configuration)
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	defer client.Close()
+	getConcurrently(t, client, 1)
+
+	time.Sleep(1500 * time.Millisecond)
+	if count := tokenRequests(server); count != 2 {
+		t.Fatalf("Expected the token to be refreshed in the background after 1s but there were %d token requests", count)
+	}
+	getConcurrently(t, client, 1)
+	if count := tokenRequests(server); count != 2 {
+		t.Errorf("Expected the refreshed token to be used but there were %d token requests", count)
+	}
+}
+
+func TestFailedBackgroundTokenRefreshIsRetried(t *testing.T) {
+	server := newSecureServer(4)
+	defer server.Close()
+	configuration := server.Configuration()
+	configuration.TokenRefreshRatio = 0.25
+	client, err := lm.BuildClient(configuration)
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	defer client.Close()
+	getConcurrently(t, client, 1)
+
+	// The refresh after 1s fails, and is retried after half the remaining 3s lifetime of the token
+	server.InjectError(http.MethodPost, "/oauth/token", http.StatusServiceUnavailable, 1)
+	time.Sleep(1250 * time.Millisecond)
+	if count := tokenRequests(server); count != 2 {
+		t.Fatalf("Expected the background refresh to have been attempted after 1s but there were %d token requests", count)
+	}
+	time.Sleep(1750 * time.Millisecond)
+	if count := tokenRequests(server); count != 3 {
+		t.Fatalf("Expected the failed background refresh to be retried but there were %d token requests", count)
+	}
+	getConcurrently(t, client, 1)
+	if count := tokenRequests(server); count != 3 {
+		t.Errorf("Expected the token obtained by the retry to be used but there were %d token requests", count)
+	}
+}
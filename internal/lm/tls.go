@@ -0,0 +1,212 @@
+package lm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var tlsLog = logf.Log.WithName("lm_tls")
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+const defaultMinTLSVersion = "1.2"
+
+// fileState identifies a version of a file on disk so changes can be detected without re-reading it
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// tlsReloader holds the CA bundle and client certificate used for connections to LM, re-reading them
+// when the files on disk change (e.g. a mounted Secret is updated)
+type tlsReloader struct {
+	tlsConfiguration TLSConfiguration
+	serverName       string
+
+	mu         sync.Mutex
+	caState    fileState
+	rootCAs    *x509.CertPool
+	certState  fileState
+	keyState   fileState
+	clientCert *tls.Certificate
+}
+
+// buildTLSConfig returns the TLS configuration to be used on all connections to LM
+func buildTLSConfig(lmConfiguration *LMConfiguration) (*tls.Config, error) {
+	tlsConfiguration := lmConfiguration.TLS
+	minVersionStr := tlsConfiguration.MinVersion
+	if minVersionStr == "" {
+		minVersionStr = defaultMinTLSVersion
+	}
+	minVersion, ok := tlsVersions[minVersionStr]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported TLS minVersion %q, must be one of 1.0, 1.1, 1.2 or 1.3", minVersionStr)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: tlsConfiguration.ServerName,
+	}
+
+	if tlsConfiguration.InsecureSkipVerify {
+		tlsLog.Info("WARNING: TLS verification of the LM server certificate is disabled (insecureSkipVerify: true)", LogKeys.URL, lmConfiguration.Base)
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if (tlsConfiguration.ClientCertificate == "") != (tlsConfiguration.ClientKey == "") {
+		return nil, fmt.Errorf("Both clientCertificate and clientKey must be set to enable mutual TLS")
+	}
+
+	if tlsConfiguration.CABundle == "" && tlsConfiguration.ClientCertificate == "" {
+		// System roots and standard verification
+		return tlsConfig, nil
+	}
+
+	serverName := tlsConfiguration.ServerName
+	if serverName == "" {
+		baseURL, err := url.Parse(lmConfiguration.Base)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse LM base URL %q: %s", lmConfiguration.Base, err)
+		}
+		serverName = baseURL.Hostname()
+	}
+	reloader := &tlsReloader{
+		tlsConfiguration: tlsConfiguration,
+		serverName:       serverName,
+	}
+
+	if tlsConfiguration.CABundle != "" {
+		if _, err := reloader.getRootCAs(); err != nil {
+			return nil, err
+		}
+		if !tlsConfiguration.InsecureSkipVerify {
+			// The standard verification only accepts a fixed pool of roots. To pick up changes to the CA bundle
+			// the chain is instead verified in VerifyPeerCertificate, against the latest roots read from disk
+			tlsConfig.InsecureSkipVerify = true
+			tlsConfig.VerifyPeerCertificate = reloader.verifyPeerCertificate
+		}
+	}
+
+	if tlsConfiguration.ClientCertificate != "" {
+		if _, err := reloader.getClientCertificate(nil); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.getClientCertificate
+	}
+
+	return tlsConfig, nil
+}
+
+func (reloader *tlsReloader) getRootCAs() (*x509.CertPool, error) {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	path := reloader.tlsConfiguration.CABundle
+	state, err := statFile(path)
+	if err != nil {
+		if reloader.rootCAs != nil {
+			tlsLog.Error(err, "Unable to check CA bundle for changes, continuing with previously loaded bundle", "path", path)
+			return reloader.rootCAs, nil
+		}
+		return nil, fmt.Errorf("Unable to read CA bundle %s: %s", path, err)
+	}
+	if reloader.rootCAs != nil && state == reloader.caState {
+		return reloader.rootCAs, nil
+	}
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return reloader.onReloadError(reloader.rootCAs, fmt.Errorf("Unable to read CA bundle %s: %s", path, err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return reloader.onReloadError(reloader.rootCAs, fmt.Errorf("No valid PEM certificates found in CA bundle %s", path))
+	}
+	if reloader.rootCAs != nil {
+		tlsLog.Info("CA bundle changed, reloaded", "path", path)
+	}
+	reloader.rootCAs = pool
+	reloader.caState = state
+	return pool, nil
+}
+
+func (reloader *tlsReloader) onReloadError(previous *x509.CertPool, err error) (*x509.CertPool, error) {
+	if previous != nil {
+		tlsLog.Error(err, "Unable to reload CA bundle, continuing with previously loaded bundle")
+		return previous, nil
+	}
+	return nil, err
+}
+
+func (reloader *tlsReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	certPath := reloader.tlsConfiguration.ClientCertificate
+	keyPath := reloader.tlsConfiguration.ClientKey
+	certState, certErr := statFile(certPath)
+	keyState, keyErr := statFile(keyPath)
+	if certErr == nil && keyErr == nil && reloader.clientCert != nil && certState == reloader.certState && keyState == reloader.keyState {
+		return reloader.clientCert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		if reloader.clientCert != nil {
+			tlsLog.Error(err, "Unable to reload client certificate, continuing with previously loaded certificate", "certificate", certPath, "key", keyPath)
+			return reloader.clientCert, nil
+		}
+		return nil, fmt.Errorf("Unable to load client certificate %s and key %s: %s", certPath, keyPath, err)
+	}
+	if reloader.clientCert != nil {
+		tlsLog.Info("Client certificate changed, reloaded", "certificate", certPath, "key", keyPath)
+	}
+	reloader.clientCert = &cert
+	reloader.certState = certState
+	reloader.keyState = keyState
+	return reloader.clientCert, nil
+}
+
+func (reloader *tlsReloader) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("LM server did not present a certificate")
+	}
+	rootCAs, err := reloader.getRootCAs()
+	if err != nil {
+		return err
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return fmt.Errorf("Unable to parse certificate presented by LM server: %s", err)
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         rootCAs,
+		Intermediates: intermediates,
+		DNSName:       reloader.serverName,
+	})
+	return err
+}
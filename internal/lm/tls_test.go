@@ -0,0 +1,150 @@
+package lm_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+)
+
+// writeCertificate writes a certificate to a PEM file, moving its modification time on so a change to the file is always seen
+func writeCertificate(t *testing.T, path string, cert *x509.Certificate, modTime time.Time) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write certificate: %s", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time of certificate: %s", err)
+	}
+}
+
+// newUnrelatedCA returns a self-signed CA certificate which has not signed the certificate of the fake LM server
+func newUnrelatedCA(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Unrelated CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %s", err)
+	}
+	return cert
+}
+
+func TestTLSVerification(t *testing.T) {
+	server := fake.NewTLSServer()
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "lm-tls")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	serverCA := filepath.Join(dir, "server-ca.crt")
+	writeCertificate(t, serverCA, server.Certificate(), time.Now())
+	unrelatedCA := filepath.Join(dir, "unrelated-ca.crt")
+	writeCertificate(t, unrelatedCA, newUnrelatedCA(t), time.Now())
+
+	tests := []struct {
+		name      string
+		tls       lm.TLSConfiguration
+		expectErr bool
+	}{
+		{name: "system roots", tls: lm.TLSConfiguration{}, expectErr: true},
+		{name: "CA bundle", tls: lm.TLSConfiguration{CABundle: serverCA}},
+		{name: "CA bundle of another CA", tls: lm.TLSConfiguration{CABundle: unrelatedCA}, expectErr: true},
+		{name: "CA bundle with server name not on the certificate", tls: lm.TLSConfiguration{CABundle: serverCA, ServerName: "lm.invalid"}, expectErr: true},
+		{name: "insecureSkipVerify", tls: lm.TLSConfiguration{InsecureSkipVerify: true}},
+		{name: "insecureSkipVerify with CA bundle of another CA", tls: lm.TLSConfiguration{CABundle: unrelatedCA, InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configuration := server.Configuration()
+			configuration.TLS = tt.tls
+			client, err := lm.BuildClient(configuration)
+			if err != nil {
+				t.Fatalf("Failed to build LM client: %s", err)
+			}
+			defer client.Close()
+			_, _, err = client.GetAssemblyByName("a1")
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected the LM server certificate to be rejected")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("Expected the LM server certificate to be accepted: %s", err)
+			}
+		})
+	}
+}
+
+func TestCABundleReload(t *testing.T) {
+	server := fake.NewTLSServer()
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "lm-tls")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	caBundle := filepath.Join(dir, "ca.crt")
+	loaded := time.Now().Add(-time.Minute)
+	writeCertificate(t, caBundle, newUnrelatedCA(t), loaded)
+
+	configuration := server.Configuration()
+	configuration.TLS = lm.TLSConfiguration{CABundle: caBundle}
+	client, err := lm.BuildClient(configuration)
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	defer client.Close()
+	if _, _, err := client.GetAssemblyByName("a1"); err == nil {
+		t.Fatalf("Expected the LM server certificate to be rejected before the CA bundle is replaced")
+	}
+
+	// Replaced in place, as when a mounted Secret is updated, without building a new client
+	writeCertificate(t, caBundle, server.Certificate(), loaded.Add(30*time.Second))
+	if _, _, err := client.GetAssemblyByName("a1"); err != nil {
+		t.Errorf("Expected the LM server certificate to be accepted once the CA bundle is replaced: %s", err)
+	}
+}
+
+func TestInvalidTLSConfiguration(t *testing.T) {
+	tests := []struct {
+		name string
+		tls  lm.TLSConfiguration
+	}{
+		{name: "unsupported minVersion", tls: lm.TLSConfiguration{MinVersion: "1.4"}},
+		{name: "client certificate without key", tls: lm.TLSConfiguration{ClientCertificate: "/tmp/tls.crt"}},
+		{name: "missing CA bundle", tls: lm.TLSConfiguration{CABundle: "/does/not/exist/ca.crt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := lm.BuildClient(&lm.LMConfiguration{Base: "https://lm:8290", TLS: tt.tls}); err == nil {
+				t.Errorf("Expected the TLS configuration to be rejected")
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -56,15 +57,300 @@ var ProcessStatus = &processStatus{
 	Failed:     "Failed",
 }
 
+type conditionTypes struct {
+	Ready                  string
+	Synced                 string
+	Progressing            string
+	Degraded               string
+	Suspended              string
+	Drifted                string
+	WaitingForDependencies string
+}
+
+var ConditionTypes = &conditionTypes{
+	Ready:                  "Ready",
+	Synced:                 "Synced",
+	Progressing:            "Progressing",
+	Degraded:               "Degraded",
+	Suspended:              "Suspended",
+	Drifted:                "Drifted",
+	WaitingForDependencies: "WaitingForDependencies",
+}
+
+type deletionPolicies struct {
+	Delete string
+	Orphan string
+}
+
+var DeletionPolicies = &deletionPolicies{
+	Delete: "Delete",
+	Orphan: "Orphan",
+}
+
+type adoptionPolicies struct {
+	Never      string
+	IfMatching string
+	Always     string
+}
+
+var AdoptionPolicies = &adoptionPolicies{
+	Never:      "Never",
+	IfMatching: "IfMatching",
+	Always:     "Always",
+}
+
+type driftPolicies struct {
+	Enforce string
+	Report  string
+	Ignore  string
+}
+
+var DriftPolicies = &driftPolicies{
+	Enforce: "Enforce",
+	Report:  "Report",
+	Ignore:  "Ignore",
+}
+
+type propertyChangeTypes struct {
+	Added   string
+	Removed string
+	Changed string
+}
+
+var PropertyChangeTypes = &propertyChangeTypes{
+	Added:   "Added",
+	Removed: "Removed",
+	Changed: "Changed",
+}
+
+// PausedAnnotation suspends reconciliation of an Assembly when set to "true", in the same way as spec.suspend
+const PausedAnnotation = "stratoss.accantosystems.com/paused"
+
+type retryModes struct {
+	Retry            string
+	StopAfterFailure string
+}
+
+var RetryModes = &retryModes{
+	Retry:            "Retry",
+	StopAfterFailure: "StopAfterFailure",
+}
+
+// RetryAnnotation clears the failed intents of an Assembly, so they are requested again, whenever its value changes (e.g. set it to the current time)
+const RetryAnnotation = "stratoss.accantosystems.com/retry"
+
+// ChangeClass is a class of change to an Assembly which can require approval
+// +kubebuilder:validation:Enum=DescriptorChange;PropertyChange;Deactivation;StateChange
+type ChangeClass string
+
+type changeClasses struct {
+	DescriptorChange ChangeClass
+	PropertyChange   ChangeClass
+	Deactivation     ChangeClass
+	StateChange      ChangeClass
+}
+
+var ChangeClasses = &changeClasses{
+	DescriptorChange: "DescriptorChange",
+	PropertyChange:   "PropertyChange",
+	Deactivation:     "Deactivation",
+	StateChange:      "StateChange",
+}
+
+// ApprovalAnnotation approves a change parked in status.pendingApproval when set to "<approver>:<specHash>", with the spec hash of the pending approval
+const ApprovalAnnotation = "stratoss.accantosystems.com/approval"
+
+type outputKinds struct {
+	ConfigMap string
+	Secret    string
+}
+
+var OutputKinds = &outputKinds{
+	ConfigMap: "ConfigMap",
+	Secret:    "Secret",
+}
+
+type modes struct {
+	Apply string
+	Plan  string
+}
+
+var Modes = &modes{
+	Apply: "Apply",
+	Plan:  "Plan",
+}
+
+// DryRunAnnotation puts an Assembly in Plan mode when set to "true", in the same way as spec.mode
+const DryRunAnnotation = "stratoss.accantosystems.com/dry-run"
+
+// DeletionPolicyAnnotation overrides spec.deletionPolicy when set on an Assembly (to Delete or Orphan)
+const DeletionPolicyAnnotation = "stratoss.accantosystems.com/deletion-policy"
+
 // AssemblySpec defines the desired state of Assembly
 // +k8s:openapi-gen=true
 type AssemblySpec struct {
+	// The name of the Assembly in LM. When not set, the name is derived from the name of this resource using the naming strategy configured on the operator.
+	// Cannot be changed once the Assembly has been named
+	AssemblyName string `json:"assemblyName,omitempty"`
 	// The descriptor name from which this Assembly will be modelled (in the form of "assembly::<name>::<version>")
 	DescriptorName string `json:"descriptorName"`
 	// The final intended state that the Assembly should be in
 	IntendedState string `json:"intendedState"`
 	// An optional map of name and string value properties supplied to configure the Assembly (valid values are properties defined on the descriptor in use)
 	Properties map[string]string `json:"properties"`
+	// Optional list of properties with values read from keys of Secrets or ConfigMaps in the same namespace as the Assembly. A property set here takes precedence over the same property in properties
+	PropertiesFrom []PropertySource `json:"propertiesFrom,omitempty"`
+	// Names of properties which are never compared with their values in LM, such as values computed by LM
+	IgnoreProperties []string `json:"ignoreProperties,omitempty"`
+	// What happens to the Assembly in LM when this resource is deleted: Delete (the default) removes it from LM, Orphan leaves it running.
+	// Can be overridden with the stratoss.accantosystems.com/deletion-policy annotation
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// Whether an Assembly which already exists in LM with the same name, not created by this resource, is taken over: Never, IfMatching (the default)
+	// when its descriptor matches descriptorName, or Always. An Assembly which is not adopted is left untouched and reported with a Degraded condition
+	// +kubebuilder:validation:Enum=Never;IfMatching;Always
+	AdoptionPolicy string `json:"adoptionPolicy,omitempty"`
+	// Suspends reconciliation of the Assembly: the status continues to be refreshed from LM but no intents are requested, including deletion.
+	// Can also be set with the stratoss.accantosystems.com/paused annotation
+	Suspend bool `json:"suspend,omitempty"`
+	// Controls if and when an intent is requested again after its process fails
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+	// Controls how often LM is checked for the progress of processes and for changes made outside of the operator. Unset fields use the defaults configured on the operator
+	Polling *PollingPolicy `json:"polling,omitempty"`
+	// What happens when the Assembly is changed in LM, outside of the operator, so it no longer matches this spec: Enforce (the default) reverts the change,
+	// Report sets the Drifted condition and records an event listing the differences, Ignore leaves the change in place. Changes to this spec are always applied
+	// +kubebuilder:validation:Enum=Enforce;Report;Ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+	// Apply (the default) requests intents from LM to bring the Assembly in line with this spec. Plan records the intent which would be requested
+	// in status.plannedIntent without sending anything to LM. Can also be set with the stratoss.accantosystems.com/dry-run annotation
+	// +kubebuilder:validation:Enum=Apply;Plan
+	Mode string `json:"mode,omitempty"`
+	// ConfigMaps and Secrets, owned by this resource, kept up to date with the values of properties of the Assembly in LM for use by workloads
+	Outputs []Output `json:"outputs,omitempty"`
+	// Assemblies which must reach a state before this Assembly is created or its state is changed. This Assembly is deleted from LM before any of them
+	DependsOn []AssemblyDependency `json:"dependsOn,omitempty"`
+	// Classes of change which are only requested from LM once approved with the stratoss.accantosystems.com/approval annotation
+	ApprovalPolicy *ApprovalPolicy `json:"approvalPolicy,omitempty"`
+	// Restricts intents which disrupt the Assembly (Update and ChangeState, optionally Create and Delete) to maintenance windows.
+	// An intent needed outside of a window is deferred until the next window opens
+	Maintenance *MaintenancePolicy `json:"maintenance,omitempty"`
+}
+
+// Controls when intents which disrupt the Assembly may be requested from LM
+// +k8s:openapi-gen=true
+type MaintenancePolicy struct {
+	// Windows in which intents may be requested
+	Windows []MaintenanceWindow `json:"windows,omitempty"`
+	// A ConfigMap, in the same namespace as the Assembly, with further windows shared between Assemblies. The windows are listed,
+	// in the same form as windows, as YAML under the "windows" key
+	WindowsFrom *corev1.LocalObjectReference `json:"windowsFrom,omitempty"`
+	// Also defer Create until a window opens
+	IncludeCreate bool `json:"includeCreate,omitempty"`
+	// Also defer Delete, when this resource is deleted, until a window opens
+	IncludeDelete bool `json:"includeDelete,omitempty"`
+}
+
+// A recurring window in which intents may be requested
+// +k8s:openapi-gen=true
+type MaintenanceWindow struct {
+	// Cron schedule of the times the window opens, in the standard five field form: minute hour day-of-month month day-of-week (e.g. "0 2 * * SAT")
+	Schedule string `json:"schedule"`
+	// How long the window stays open for (e.g. 4h)
+	Duration metav1.Duration `json:"duration"`
+	// IANA name of the time zone the schedule is in (e.g. Europe/London). Defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Controls which changes to the Assembly need approval before they are requested from LM
+// +k8s:openapi-gen=true
+type ApprovalPolicy struct {
+	// Classes of change needing approval: DescriptorChange (an Update to a new descriptor), PropertyChange (an Update of properties only),
+	// Deactivation (a ChangeState to Inactive) and StateChange (any ChangeState)
+	RequireApprovalFor []ChangeClass `json:"requireApprovalFor"`
+}
+
+// A reference to another Assembly resource and the state it must be in
+// +k8s:openapi-gen=true
+type AssemblyDependency struct {
+	// Name of the Assembly resource
+	Name string `json:"name"`
+	// Namespace of the Assembly resource, which must be the namespace of this Assembly (the default) as dependencies in other namespaces are not supported
+	Namespace string `json:"namespace,omitempty"`
+	// State the Assembly must be in, in LM. Defaults to Active
+	// +kubebuilder:validation:Enum=Created;Installed;Inactive;Active
+	State string `json:"state,omitempty"`
+}
+
+// A ConfigMap or Secret, in the same namespace as the Assembly, to publish properties of the Assembly in LM to
+// +k8s:openapi-gen=true
+type Output struct {
+	// Kind of object: ConfigMap (the default) or Secret. Properties read from Secrets can only be published to a Secret
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind,omitempty"`
+	// Name of the ConfigMap or Secret. It is created by the operator and must not already exist, unless owned by this resource
+	Name string `json:"name"`
+	// Properties of the Assembly to publish. A property not yet set on the Assembly in LM is left out
+	Properties []OutputProperty `json:"properties"`
+}
+
+// A property of the Assembly in LM published to a key of a ConfigMap or Secret
+// +k8s:openapi-gen=true
+type OutputProperty struct {
+	// Name of the property of the Assembly in LM
+	Name string `json:"name"`
+	// Key to publish the value under. Defaults to the name of the property
+	Key string `json:"key,omitempty"`
+}
+
+// Controls how often LM is checked for the progress of processes and for changes made outside of the operator
+// +k8s:openapi-gen=true
+type PollingPolicy struct {
+	// Delay before the first check on the progress of a new process
+	InitialInterval *metav1.Duration `json:"initialInterval,omitempty"`
+	// Upper limit on the delay between checks on the progress of a process, which doubles after each check
+	MaxInterval *metav1.Duration `json:"maxInterval,omitempty"`
+	// Period between checks for changes made outside of the operator, when no process is in progress (0s disables them)
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+}
+
+// Controls if and when an intent is requested again after its process fails
+// +k8s:openapi-gen=true
+type RetryPolicy struct {
+	// Retry (the default) requests a failed intent again, up to maxRetries times, with a growing delay between each attempt. StopAfterFailure never requests a failed intent again
+	// +kubebuilder:validation:Enum=Retry;StopAfterFailure
+	Mode string `json:"mode,omitempty"`
+	// Maximum number of times a failed intent is requested again before it is given up on (defaults to 3)
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+	// Delay between the failure of an intent and requesting it again
+	Backoff RetryBackoff `json:"backoff,omitempty"`
+}
+
+// Delay between the failure of an intent and requesting it again, doubled after each failure
+// +k8s:openapi-gen=true
+type RetryBackoff struct {
+	// Delay before the first retry (defaults to 30s)
+	InitialDelay *metav1.Duration `json:"initialDelay,omitempty"`
+	// Upper limit on the delay between retries (defaults to 10m)
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+}
+
+// Details a property of an Assembly with a value read from a Secret or ConfigMap
+// +k8s:openapi-gen=true
+type PropertySource struct {
+	// Name of the property
+	Name string `json:"name"`
+	// Source of the property value
+	ValueFrom PropertyValueSource `json:"valueFrom"`
+}
+
+// Source of a property value. Exactly one of secretKeyRef or configMapKeyRef must be set
+// +k8s:openapi-gen=true
+type PropertyValueSource struct {
+	// Selects a key of a Secret in the namespace of the Assembly
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// Selects a key of a ConfigMap in the namespace of the Assembly
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
 }
 
 // AssemblyStatus defines the observed state of Assembly
@@ -72,10 +358,16 @@ type AssemblySpec struct {
 type AssemblyStatus struct {
 	// ID of the Assembly
 	ID string `json:"assemblyId"`
+	// The name of the Assembly in LM, fixed when first resolved
+	AssemblyName string `json:"assemblyName,omitempty"`
 	// The current descriptor name from which this Assembly was modelled (in the form of "assembly::<name>::<version>")
 	DescriptorName string `json:"descriptorName"`
-	// An optional map of name and string value properties supplied to configure the Assembly (valid values are properties defined on the descriptor in use)
+	// The current properties of the Assembly. Values of properties read from Secrets are redacted
 	Properties map[string]string `json:"properties"`
+	// Names of the properties set by the operator on Creates and Updates of the Assembly, narrowed to those desired once one completes. A property removed from the spec is only removed from LM if it is listed here
+	ManagedProperties []string `json:"managedProperties,omitempty"`
+	// Differences between the desired and current properties found on the last reconcile, which are applied to LM with an Update. Values of properties read from Secrets are redacted
+	PendingChanges []PropertyChange `json:"pendingChanges,omitempty"`
 	// State of the Assembly at last reconcile
 	// +kubebuilder:validation:Enum=Failed;Created;Installed;Inactive;Broken;Active;NotFound;None;
 	State string `json:"state"`
@@ -83,6 +375,117 @@ type AssemblyStatus struct {
 	LastProcess Process `json:"lastProcess,omitempty"`
 	// Details the success to synchronize this Assembly with LM
 	SyncState SyncState `json:"syncState,omitempty"`
+	// Hash of the desired state (the spec, and versions of any Secrets and ConfigMaps it references) the Assembly in LM was last found to match.
+	// Differences found whilst the desired state still has this hash were made in LM, outside of the operator
+	SyncedSpecHash string `json:"syncedSpecHash,omitempty"`
+	// Intents whose processes failed, held back from being requested again until their next retry time
+	FailedIntents []FailedIntent `json:"failedIntents,omitempty"`
+	// The intent which would be requested from LM, computed whilst the Assembly is in Plan mode
+	PlannedIntent *PlannedIntent `json:"plannedIntent,omitempty"`
+	// A change held back until it is approved, as required by spec.approvalPolicy
+	PendingApproval *PendingApproval `json:"pendingApproval,omitempty"`
+	// The maintenance window which is open, or else the next to open, when spec.maintenance is set
+	NextMaintenanceWindow *ScheduledWindow `json:"nextMaintenanceWindow,omitempty"`
+	// The .metadata.generation of the Assembly last acted on by the operator. Equal to .metadata.generation once the latest change to the spec has been picked up
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Time of the last reconcile which synchronized with LM without errors
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Hash of the spec, operator annotations and versions of referenced Secrets and ConfigMaps when the last reconcile left nothing to do.
+	// Reconciles are skipped whilst these are unchanged, until the next resync with LM is due
+	LastSyncHash string `json:"lastSyncHash,omitempty"`
+	// Time the last intent was requested from LM
+	LastIntentTime *metav1.Time `json:"lastIntentTime,omitempty"`
+	// Hash of the spec which produced the last intent requested from LM
+	LastIntentSpecHash string `json:"lastIntentSpecHash,omitempty"`
+	// Latest observations of the Assembly's state (Ready, Synced, Progressing, Degraded, Suspended, Drifted and WaitingForDependencies)
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// Details one aspect of the current state of an Assembly
+// +k8s:openapi-gen=true
+type Condition struct {
+	// Type of condition
+	Type string `json:"type"`
+	// Status of the condition
+	// +kubebuilder:validation:Enum=True;False;Unknown;
+	Status corev1.ConditionStatus `json:"status"`
+	// The .metadata.generation of the Assembly the condition was set against
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Last time the condition changed from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// A CamelCase reason for the condition's last transition
+	Reason string `json:"reason"`
+	// A human readable message with details about the transition
+	Message string `json:"message"`
+}
+
+// Details a difference between the desired and current value of a property
+// +k8s:openapi-gen=true
+type PropertyChange struct {
+	// Name of the property
+	Name string `json:"name"`
+	// Type of change: Added (not set in LM), Removed (no longer in the spec) or Changed (value differs)
+	// +kubebuilder:validation:Enum=Added;Removed;Changed
+	Change string `json:"change"`
+	// Desired value of the property
+	DesiredValue string `json:"desiredValue,omitempty"`
+	// Current value of the property in LM
+	ObservedValue string `json:"observedValue,omitempty"`
+}
+
+// Details an intent whose process failed
+// +k8s:openapi-gen=true
+type FailedIntent struct {
+	// Type of intent
+	// +kubebuilder:validation:Enum=Create;ChangeState;Update;Delete;
+	IntentType string `json:"intentType"`
+	// Number of consecutive failures of the intent
+	Failures int32 `json:"failures"`
+	// Time after which the intent may be requested again. Not set once the intent is terminal
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+	// True when the intent will not be requested again until the spec changes or the stratoss.accantosystems.com/retry annotation is changed
+	Terminal bool `json:"terminal"`
+	// Hash of the desired state (descriptor, intended state and properties, including the versions of referenced Secrets and ConfigMaps)
+	// which produced the failed intent. A change to the desired state clears the failure
+	SpecHash string `json:"specHash"`
+	// Value of the stratoss.accantosystems.com/retry annotation when the intent failed. A change to the annotation clears the failure
+	RetryAnnotation string `json:"retryAnnotation,omitempty"`
+}
+
+// An intent computed in Plan mode, which is requested from LM once the Assembly is switched back to Apply
+// +k8s:openapi-gen=true
+type PlannedIntent struct {
+	// Type of intent
+	// +kubebuilder:validation:Enum=Create;ChangeState;Update;Delete;
+	IntentType string `json:"intentType"`
+	// The JSON payload which would be sent to LM. Values of properties read from Secrets are redacted
+	Request string `json:"request"`
+	// Hash of the desired state (descriptor, intended state and properties, including the versions of referenced Secrets and ConfigMaps)
+	// the intent was planned from. The intent is only requested once back in Apply mode if the desired state is unchanged
+	SpecHash string `json:"specHash"`
+}
+
+// A change held back until it is approved
+// +k8s:openapi-gen=true
+type PendingApproval struct {
+	// Type of intent which will be requested once approved
+	// +kubebuilder:validation:Enum=ChangeState;Update;
+	IntentType string `json:"intentType"`
+	// Class of the change, as listed in spec.approvalPolicy
+	ChangeClass ChangeClass `json:"changeClass"`
+	// Differences between the Assembly in LM and the spec. Values of properties read from Secrets are redacted
+	Diff []string `json:"diff"`
+	// Hash of the desired state (the spec, and versions of any Secrets and ConfigMaps it references) to approve. A change to the desired state needs a new approval
+	SpecHash string `json:"specHash"`
+}
+
+// An occurrence of a maintenance window
+// +k8s:openapi-gen=true
+type ScheduledWindow struct {
+	// Time the window opens
+	Start metav1.Time `json:"start"`
+	// Time the window closes
+	End metav1.Time `json:"end"`
 }
 
 // Details the success to synchronize this Assembly with LM
@@ -109,6 +512,8 @@ type Process struct {
 	Status string `json:"status"`
 	// Describes the reason of the Status, usually only set when Failed
 	StatusReason string `json:"statusReason"`
+	// Hash of the desired state the intent which started the process was requested for
+	DesiredStateHash string `json:"desiredStateHash,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -116,6 +521,7 @@ type Process struct {
 // Assembly is the Schema for the assemblies API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=assemblies,scope=Namespaced
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type==\"Ready\")].status",name=Ready,type=string,description=Details if the Assembly has reached the desired state
 // +kubebuilder:printcolumn:JSONPath=".status.syncState.status",name=Synchronized,type=string,description=Details if the operator was able to synchronize this Assembly when handling the last event
 // +kubebuilder:printcolumn:JSONPath=".status.descriptorName",name=Descriptor,type=string,description=The current observed Descriptor of the Assembly
 // +kubebuilder:printcolumn:JSONPath=".status.state",name=State,type=string,description=The current observed State of the Assembly
@@ -0,0 +1,75 @@
+package assembly
+
+import (
+	"reflect"
+	"strings"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+)
+
+// parseApproval splits the value of the approval annotation into the approver and the approved spec hash
+func parseApproval(value string) (approver string, approvedHash string, ok bool) {
+	separator := strings.LastIndex(value, ":")
+	if separator <= 0 || separator == len(value)-1 {
+		return "", "", false
+	}
+	return value[:separator], value[separator+1:], true
+}
+
+// approvalRequiredFor returns the first of the classes of a change which the approval policy of the Assembly lists, or an empty string if none are
+func approvalRequiredFor(spec stratossv1alpha1.AssemblySpec, classes ...stratossv1alpha1.ChangeClass) stratossv1alpha1.ChangeClass {
+	if spec.ApprovalPolicy == nil {
+		return ""
+	}
+	for _, class := range classes {
+		for _, required := range spec.ApprovalPolicy.RequireApprovalFor {
+			if class == required {
+				return class
+			}
+		}
+	}
+	return ""
+}
+
+// stateChangeClasses returns the classes of a change of state to the intended state
+func stateChangeClasses(intendedState string) []stratossv1alpha1.ChangeClass {
+	if intendedState == stratossv1alpha1.AssemblyStates.Inactive {
+		return []stratossv1alpha1.ChangeClass{stratossv1alpha1.ChangeClasses.Deactivation, stratossv1alpha1.ChangeClasses.StateChange}
+	}
+	return []stratossv1alpha1.ChangeClass{stratossv1alpha1.ChangeClasses.StateChange}
+}
+
+// checkApproval holds back an intent making a change of a class listed in the approval policy, parking it in status.pendingApproval,
+// until the approval annotation names the hash of the current desired state. The annotation is removed once the approved intent is requested
+func (sync *AssemblySynchronizer) checkApproval(intentType string, classes ...stratossv1alpha1.ChangeClass) (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	class := approvalRequiredFor(k8sInstance.Spec, classes...)
+	if class == "" {
+		return false
+	}
+	hash := sync.desiredStateHash()
+	approval := k8sInstance.GetAnnotations()[stratossv1alpha1.ApprovalAnnotation]
+	approver, approvedHash, ok := parseApproval(approval)
+	if ok && approvedHash == hash {
+		sync.approval = approval
+		sync.logger.Info("Change approved", "intentType", intentType, "changeClass", class, "approver", approver, "specHash", hash)
+		sync.recordNormalEvent(EventReasons.Approved, "%s (%s) approved by %s for spec hash %s", intentType, class, approver, hash)
+		return false
+	}
+	pending := &stratossv1alpha1.PendingApproval{
+		IntentType:  intentType,
+		ChangeClass: class,
+		Diff:        sync.describeDifferences(),
+		SpecHash:    hash,
+	}
+	sync.logger.Info("Change requires approval, holding back intent", "intentType", intentType, "changeClass", class, "specHash", hash)
+	if previous := k8sInstance.Status.PendingApproval; previous == nil || !reflect.DeepEqual(*previous, *pending) {
+		sync.recordNormalEvent(EventReasons.ApprovalRequired, "%s (%s) requires approval, approve with annotation %s=<approver>:%s: %s",
+			intentType, class, stratossv1alpha1.ApprovalAnnotation, hash, strings.Join(pending.Diff, "; "))
+	}
+	k8sInstance.Status.PendingApproval = pending
+	sync.approvalPending = true
+	sync.needsStatusUpdate = true
+	sync.stopSync = true
+	return sync.stopSync
+}
@@ -0,0 +1,122 @@
+package assembly
+
+import (
+	"testing"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newApprovalTestEnv returns an environment with an Active Assembly which needs approval for a change of its properties
+func newApprovalTestEnv(t *testing.T) *testEnv {
+	spec := activeSpec()
+	spec.ApprovalPolicy = &stratossv1alpha1.ApprovalPolicy{RequireApprovalFor: []stratossv1alpha1.ChangeClass{stratossv1alpha1.ChangeClasses.PropertyChange}}
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	return env
+}
+
+func setSize(size string) func(instance *stratossv1alpha1.Assembly) {
+	return func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = size }
+}
+
+// approve approves the change pending approval on the Assembly
+func (env *testEnv) approve(name string) {
+	env.t.Helper()
+	pending := env.get(name).Status.PendingApproval
+	if pending == nil {
+		env.t.Fatalf("Expected a change to be pending approval")
+	}
+	env.edit(name, func(instance *stratossv1alpha1.Assembly) {
+		instance.SetAnnotations(map[string]string{stratossv1alpha1.ApprovalAnnotation: "jane.doe:" + pending.SpecHash})
+	})
+}
+
+func TestReconcileRequestsApprovedIntentOnce(t *testing.T) {
+	env := newApprovalTestEnv(t)
+	defer env.close()
+
+	env.edit("a1", setSize("large"))
+	env.mustReconcile("a1")
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 0 {
+		t.Fatalf("Expected the Update to be held back until approved but found %d upgradeAssembly requests", len(requests))
+	}
+	if status, reason := conditionStatus(env.get("a1"), stratossv1alpha1.ConditionTypes.Ready); status != corev1.ConditionFalse || reason != ConditionReasons.AwaitingApproval {
+		t.Errorf("Expected Ready to be False (%s) but was %s (%s)", ConditionReasons.AwaitingApproval, status, reason)
+	}
+
+	env.approve("a1")
+	env.events()
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.Properties["size"] == "large" && isActive(instance)
+	})
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 1 {
+		t.Fatalf("Expected the approved Update to be requested once but found %d upgradeAssembly requests", len(requests))
+	}
+	instance := env.get("a1")
+	if approval, found := instance.GetAnnotations()[stratossv1alpha1.ApprovalAnnotation]; found {
+		t.Errorf("Expected the approval annotation to be removed once the intent was requested but was %q", approval)
+	}
+	if instance.Status.PendingApproval != nil {
+		t.Errorf("Expected no change to be pending approval but was %+v", instance.Status.PendingApproval)
+	}
+	if events := env.events(); !hasEvent(events, EventReasons.Approved) {
+		t.Errorf("Expected an %s event but found %v", EventReasons.Approved, events)
+	}
+
+	// Returning to an approved desired state needs a new approval, as the approval was used by the intent it approved
+	env.edit("a1", setSize("small"))
+	env.mustReconcile("a1")
+	env.approve("a1")
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.Properties["size"] == "small" && isActive(instance)
+	})
+	env.edit("a1", setSize("large"))
+	env.mustReconcile("a1")
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 2 {
+		t.Errorf("Expected the return to an earlier desired state to be held back until approved again but found %d upgradeAssembly requests", len(requests))
+	}
+	if pending := env.get("a1").Status.PendingApproval; pending == nil {
+		t.Errorf("Expected the change to be pending approval")
+	}
+}
+
+func TestReconcileKeepsApprovalChangedDuringReconcile(t *testing.T) {
+	env := newApprovalTestEnv(t)
+	defer env.close()
+	env.edit("a1", setSize("large"))
+	env.mustReconcile("a1")
+	env.approve("a1")
+
+	sync := newTestSynchronizer(env.get("a1"))
+	sync.apiReader = env.k8sClient
+	sync.reconcileRequest = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "a1"}}
+	sync.approval = "someone.else:other"
+	if stopSync := sync.removeK8sInstanceApproval(); stopSync {
+		t.Fatalf("Expected the approval annotation to be checked without error")
+	}
+	if _, found := env.get("a1").GetAnnotations()[stratossv1alpha1.ApprovalAnnotation]; !found {
+		t.Errorf("Expected an approval annotation other than the one used to be kept")
+	}
+}
+
+func TestReconcileClearsApprovalNoLongerNeeded(t *testing.T) {
+	env := newApprovalTestEnv(t)
+	defer env.close()
+	env.edit("a1", setSize("large"))
+	env.mustReconcile("a1")
+
+	// Ready is decided after the bookkeeping clears the pending approval, so is correct in the same reconcile
+	env.edit("a1", setSize("small"))
+	env.mustReconcile("a1")
+	instance := env.get("a1")
+	if instance.Status.PendingApproval != nil {
+		t.Errorf("Expected the pending approval to be cleared once the change is undone but was %+v", instance.Status.PendingApproval)
+	}
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Ready); status != corev1.ConditionTrue {
+		t.Errorf("Expected Ready to be True once no change is pending approval but was %s (%s)", status, reason)
+	}
+}
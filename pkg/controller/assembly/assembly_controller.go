@@ -2,6 +2,7 @@ package assembly
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -11,11 +12,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -25,29 +30,46 @@ var log = logf.Log.WithName("controller_assembly")
 const assemblyFinalizer = "finalizer.assemblies.stratoss.accantosystems.com"
 const stateError = "ERROR"
 
+// Name of the controller, which also names its work queue
+const controllerName = "assembly-controller"
+
+// Name of the field manager recorded against the fields of an Assembly written by the operator
+const fieldManager = "assembly-operator"
+
+// Time to wait before checking whether an adoption conflict has been resolved in LM
+const adoptionConflictRequeueDelay = 30 * time.Second
+
 type logKeys struct {
 	AssemblyName          string
+	LMAssemblyName        string
 	AssemblyID            string
 	ProcessID             string
 	ProcessStatus         string
 	IntendedState         string
 	NumberOfErrors        string
 	PropertyName          string
+	PropertyChange        string
 	DesiredPropertyValue  string
 	ObservedPropertyName  string
 	ObservedPropertyValue string
+	Output                string
+	OutputKind            string
 }
 
 var LogKeys = &logKeys{
 	AssemblyName:          "assemblyName",
+	LMAssemblyName:        "lmAssemblyName",
 	AssemblyID:            "assemblyId",
 	ProcessID:             "processId",
 	ProcessStatus:         "processStatus",
 	IntendedState:         "intendedState",
 	NumberOfErrors:        "errorCount",
 	PropertyName:          "propertyName",
+	PropertyChange:        "propertyChange",
 	DesiredPropertyValue:  "desiredPropertyValue",
 	ObservedPropertyValue: "observedPropertyValue",
+	Output:                "output",
+	OutputKind:            "outputKind",
 }
 
 // Add creates a new Assembly Controller and adds it to the Manager. The Manager will set fields on the Controller
@@ -62,34 +84,89 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
-	lmConfiguration, err := lm.ReadLMConfiguration()
+	namer, err := buildAssemblyNamer(controllerOptions.namingStrategy, controllerOptions.nameTemplate)
+	if err != nil {
+		return &AssemblyReconciler{}, err
+	}
+	if err := controllerOptions.polling.validate(); err != nil {
+		return &AssemblyReconciler{}, err
+	}
+	if err := controllerOptions.rateLimit.validate(); err != nil {
+		return &AssemblyReconciler{}, err
+	}
+	if controllerOptions.maxConcurrentReconciles < 1 {
+		return &AssemblyReconciler{}, fmt.Errorf("Max concurrent reconciles must be at least 1")
+	}
+	lmClient, err := lm.BuildReloadingClient(lm.DefaultConfigurationPath)
 	if err != nil {
 		return &AssemblyReconciler{}, err
 	}
+	// Watch the LM configuration and credentials for changes whilst the Manager is running
+	if err := mgr.Add(lmClient); err != nil {
+		return &AssemblyReconciler{}, err
+	}
 	return &AssemblyReconciler{
 		k8sClient: mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
 		scheme:    mgr.GetScheme(),
-		lmClient:  lm.BuildClient(lmConfiguration),
+		lmClient:  lmClient,
+		recorder:  mgr.GetEventRecorderFor("assembly-operator"),
+		namer:     namer,
+		poller:    newProcessPoller(controllerOptions.polling),
 	}, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("assembly-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: controllerOptions.maxConcurrentReconciles,
+	})
 	if err != nil {
 		return err
 	}
+	if err := useRateLimiter(c, controllerName, controllerOptions.rateLimit.newRateLimiter()); err != nil {
+		return err
+	}
 
-	// Watch for changes to primary resource Assembly
-	err = c.Watch(&source.Kind{Type: &stratossv1alpha1.Assembly{}}, &handler.EnqueueRequestForObject{})
+	// Watch for changes to primary resource Assembly, ignoring updates to its status
+	err = c.Watch(&source.Kind{Type: &stratossv1alpha1.Assembly{}}, &handler.EnqueueRequestForObject{}, predicate.Funcs{UpdateFunc: needsReconcile})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Assemblies, including their status, and requeue the Assemblies which depend on them or which they depend on
+	err = c.Watch(&source.Kind{Type: &stratossv1alpha1.Assembly{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: relatedAssemblies(mgr.GetClient()),
+	})
 	if err != nil {
 		return err
 	}
 
-	// TODO(user): Modify this to be the types you create that are owned by the primary resource
-	// Watch for changes to secondary resource Pods and requeue the owner Assembly
-	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForOwner{
+	// Watch for changes to Secrets and ConfigMaps and requeue the Assemblies with properties read from them
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: referencingAssemblies(mgr.GetClient(), referencesSecret),
+	})
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: referencingAssemblies(mgr.GetClient(), referencesConfigMap),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the ConfigMaps and Secrets outputs are published to and requeue the owner Assembly
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &stratossv1alpha1.Assembly{},
+	})
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &stratossv1alpha1.Assembly{},
 	})
@@ -103,38 +180,94 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 // blank assignment to verify that AssemblyReconciler implements reconcile.Reconciler
 var _ reconcile.Reconciler = &AssemblyReconciler{}
 
-// AssemblyReconciler reconciles a Assembly object
+// AssemblyReconciler reconciles a Assembly object. Reconcile may be called for several Assemblies at once, so its fields must be safe for
+// concurrent use. State of a single reconcile is kept on an AssemblySynchronizer
 type AssemblyReconciler struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	k8sClient client.Client
+	// Reads objects from the apiserver, bypassing the cache, where the latest version is needed
+	apiReader client.Reader
 	scheme    *runtime.Scheme
-	lmClient  *lm.LMClient
+	lmClient  lm.LMClient
+	recorder  record.EventRecorder
+	namer     *assemblyNamer
+	poller    *processPoller
 }
 
 // AssemblySynchronizer carries the state of a single reconcile call
 type AssemblySynchronizer struct {
 	k8sClient           client.Client
+	apiReader           client.Reader
+	scheme              *runtime.Scheme
 	k8sInstance         *stratossv1alpha1.Assembly
 	lmClient            lm.LMClient
+	recorder            record.EventRecorder
+	namer               *assemblyNamer
+	poller              *processPoller
 	logger              logr.Logger
 	reconcileRequest    reconcile.Request
 	stopSync            bool
 	requeue             bool
-	requeueDelay        int
+	requeueDelay        time.Duration
 	errors              []error
 	updateError         bool
 	hasFinalizerChanges bool
 	needsStatusUpdate   bool
 	newProcessStarted   bool
 	isDeleted           bool
+	lmSourceOfTruth     *LMSourceOfTruth
+	// The Assembly as read at the start of the reconcile, which changes to the status are patched from
+	original *stratossv1alpha1.Assembly
+	// Properties to be sent to LM, including those read from Secrets and ConfigMaps
+	desiredProperties map[string]string
+	// Names of properties read from Secrets, which must not be shown in status or logs
+	secretProperties map[string]bool
+	// Set when an Assembly with the same name exists in LM which must not be adopted
+	adoptionConflict string
+	// Versions of the Secrets and ConfigMaps properties were read from
+	sourceVersions []string
+	// Versions of the ConfigMaps and Secrets outputs were published to
+	outputVersions []string
+	// Set once the reconcile has got past any process in progress, so has acted on the current spec
+	specObserved bool
+	// Set when the reconcile found nothing to change on the Assembly
+	inSync bool
+	// Set when an intent was planned, in place of being requested, in Plan mode
+	intentPlanned bool
+	// Set when an intent was held back until it is approved
+	approvalPending bool
+	// The value of the approval annotation which approved the intent, removed from the Assembly once the intent is requested
+	approval string
+	// Describes the intent deferred until the next maintenance window opens
+	intentDeferred string
+	// Why the maintenance windows could not be resolved, only reported when an intent is deferred to them
+	maintenanceWindowErr error
+	// Describes why the intent planned in Plan mode was not requested, as it no longer matches the Assembly
+	planOutdated string
+	// Set once the logger carries the name of the Assembly in LM, which is resolved on every sync with LM
+	loggerHasAssemblyName bool
 }
 
 type LMSourceOfTruth struct {
 	assemblyInstance      *lm.Assembly
 	assemblyInstanceFound bool
-	latestProcess         *lm.Process
-	latestProcessFound    bool
+	// The process started by the operator, as recorded on the Assembly status
+	trackedProcess      *lm.Process
+	trackedProcessFound bool
+	// The latest process on the Assembly, only fetched when there is no tracked process
+	latestProcess      *lm.Process
+	latestProcessFound bool
+}
+
+func (lmSourceOfTruth *LMSourceOfTruth) assemblyProperties() map[string]string {
+	properties := make(map[string]string)
+	if lmSourceOfTruth.assemblyInstanceFound {
+		for _, property := range lmSourceOfTruth.assemblyInstance.Properties {
+			properties[property.Name] = property.Value
+		}
+	}
+	return properties
 }
 
 func (sync *AssemblySynchronizer) onUpdateError(err error) (stopSync bool) {
@@ -162,8 +295,35 @@ func (sync *AssemblySynchronizer) fetchK8sInstance() (found bool, stopSync bool)
 	return true, false
 }
 
-func (sync *AssemblySynchronizer) updateK8sInstance() (stopSync bool) {
-	err := sync.k8sClient.Update(context.TODO(), sync.k8sInstance)
+// patchK8sInstanceFinalizers adds or removes the finalizer of the operator on the latest version of the Assembly, leaving any other finalizers
+// in place. The Assembly is read from the apiserver, as the cache may not have caught up with it yet, and the patch is made against the
+// resourceVersion read, so the Assembly is read again and the patch retried if it changes in between
+func (sync *AssemblySynchronizer) patchK8sInstanceFinalizers(finalized bool) (stopSync bool) {
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &stratossv1alpha1.Assembly{}
+		if err := sync.apiReader.Get(context.TODO(), sync.reconcileRequest.NamespacedName, latest); err != nil {
+			return err
+		}
+		finalizers := latest.GetFinalizers()
+		if finalizerContains(finalizers, assemblyFinalizer) == finalized {
+			return nil
+		}
+		if finalized {
+			finalizers = append(finalizers, assemblyFinalizer)
+		} else {
+			finalizers = finalizerRemove(finalizers, assemblyFinalizer)
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"finalizers":      finalizers,
+				"resourceVersion": latest.GetResourceVersion(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return sync.k8sClient.Patch(context.TODO(), latest, client.ConstantPatch(types.MergePatchType, patch), client.FieldOwner(fieldManager))
+	})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
@@ -171,16 +331,52 @@ func (sync *AssemblySynchronizer) updateK8sInstance() (stopSync bool) {
 			// Return and don't requeue
 			return true
 		}
-		sync.logger.Error(err, "Failed to update Assembly (CR)")
+		sync.logger.Error(err, "Failed to patch Assembly (CR) finalizers")
 		return sync.onUpdateError(err)
 	}
 	return false
 }
 
-func (sync *AssemblySynchronizer) updateK8sInstanceStatus() (stopSync bool) {
-	err := sync.k8sClient.Status().Update(context.TODO(), sync.k8sInstance)
+// removeK8sInstanceApproval removes the approval annotation from the latest version of the Assembly once the intent it approved is requested,
+// so it cannot approve the same desired state again later. An annotation changed since the reconcile read it approves something else so is kept
+func (sync *AssemblySynchronizer) removeK8sInstanceApproval() (stopSync bool) {
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &stratossv1alpha1.Assembly{}
+		if err := sync.apiReader.Get(context.TODO(), sync.reconcileRequest.NamespacedName, latest); err != nil {
+			return err
+		}
+		if latest.GetAnnotations()[stratossv1alpha1.ApprovalAnnotation] != sync.approval {
+			return nil
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations":     map[string]interface{}{stratossv1alpha1.ApprovalAnnotation: nil},
+				"resourceVersion": latest.GetResourceVersion(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return sync.k8sClient.Patch(context.TODO(), latest, client.ConstantPatch(types.MergePatchType, patch), client.FieldOwner(fieldManager))
+	})
 	if err != nil {
-		sync.logger.Error(err, "Failed to update Assembly (CR) status")
+		if errors.IsNotFound(err) {
+			return true
+		}
+		sync.logger.Error(err, "Failed to remove approval annotation from Assembly (CR)")
+		return sync.onUpdateError(err)
+	}
+	return false
+}
+
+// patchK8sInstanceStatus writes the status with a merge patch of the status subresource. No resourceVersion is sent, as the status is only
+// written by the operator, so changes made to the rest of the Assembly during the reconcile neither conflict with it nor are overwritten by it
+func (sync *AssemblySynchronizer) patchK8sInstanceStatus() (stopSync bool) {
+	patched := sync.original.DeepCopy()
+	patched.Status = *sync.k8sInstance.Status.DeepCopy()
+	err := sync.k8sClient.Status().Patch(context.TODO(), patched, client.MergeFrom(sync.original), client.FieldOwner(fieldManager))
+	if err != nil {
+		sync.logger.Error(err, "Failed to patch Assembly (CR) status")
 		return sync.onUpdateError(err)
 	}
 	return false
@@ -188,7 +384,7 @@ func (sync *AssemblySynchronizer) updateK8sInstanceStatus() (stopSync bool) {
 
 func (sync *AssemblySynchronizer) getLatestProcess() (process *lm.Process, found bool, stopSync bool) {
 	sync.logger.Info("Fetching latest Process for Assembly")
-	process, found, err := sync.lmClient.GetLatestProcess(sync.k8sInstance.Name)
+	process, found, err := sync.lmClient.GetLatestProcess(sync.assemblyName())
 	if err != nil {
 		sync.logger.Error(err, "Failed to fetch latest Process for Assembly")
 		return nil, false, sync.onLMError(err)
@@ -196,6 +392,16 @@ func (sync *AssemblySynchronizer) getLatestProcess() (process *lm.Process, found
 	return process, found, false
 }
 
+func (sync *AssemblySynchronizer) getProcessByID(processID string) (process *lm.Process, found bool, stopSync bool) {
+	sync.logger.Info("Fetching Process started for Assembly", LogKeys.ProcessID, processID)
+	process, found, err := sync.lmClient.GetProcessByID(processID)
+	if err != nil {
+		sync.logger.Error(err, "Failed to fetch Process for Assembly", LogKeys.ProcessID, processID)
+		return nil, false, sync.onLMError(err)
+	}
+	return process, found, false
+}
+
 func (sync *AssemblySynchronizer) getAssemblyByID(assemblyID string) (lmAssembly *lm.Assembly, found bool, stopSync bool) {
 	sync.logger.Info("Fetching Assembly from LM")
 	lmAssembly, found, err := sync.lmClient.GetAssemblyByID(assemblyID)
@@ -208,7 +414,7 @@ func (sync *AssemblySynchronizer) getAssemblyByID(assemblyID string) (lmAssembly
 
 func (sync *AssemblySynchronizer) getAssemblyByName() (lmAssembly *lm.Assembly, found bool, stopSync bool) {
 	sync.logger.Info("Fetching Assembly from LM")
-	lmAssembly, found, err := sync.lmClient.GetAssemblyByName(sync.k8sInstance.Name)
+	lmAssembly, found, err := sync.lmClient.GetAssemblyByName(sync.assemblyName())
 	if err != nil {
 		sync.logger.Error(err, "Failed to fetch Assembly")
 		return nil, false, sync.onLMError(err)
@@ -220,6 +426,8 @@ func (sync *AssemblySynchronizer) fetchLMSourceOfTruth() (lmSourceOfTruth *LMSou
 	k8sInstance := sync.k8sInstance
 	var assemblyInstance *lm.Assembly
 	var assemblyInstanceFound bool
+	var trackedProcess *lm.Process
+	var trackedProcessFound bool
 	var latestProcess *lm.Process
 	var latestProcessFound bool
 	// Fetch information about the Assembly
@@ -246,31 +454,57 @@ func (sync *AssemblySynchronizer) fetchLMSourceOfTruth() (lmSourceOfTruth *LMSou
 		}
 	}
 
-	// Fetch information about the latest Process
-	latestProcess, latestProcessFound, stopSync = sync.getLatestProcess()
-	if stopSync {
-		return nil, stopSync
-	} else if !latestProcessFound {
-		sync.logger.Info("Latest process not found in LM")
-	} else {
-		//Ensure the process is for this Assembly (can be a previous one with the same name if recreating)
-		if latestProcess.AssemblyID == assemblyInstance.ID {
-			sync.logger.Info("Latest process found in LM")
+	// Fetch information about the Process started by the operator
+	lastProcess := k8sInstance.Status.LastProcess
+	if lastProcess.ID != "" {
+		processLogger := sync.logger.WithValues(LogKeys.ProcessID, lastProcess.ID)
+		if lastProcess.Status != "" && !processIsOngoing(lastProcess.Status) {
+			// Already reached a terminal status, no need to poll it again
+			processLogger.Info("Process started by the operator has already completed", LogKeys.ProcessStatus, lastProcess.Status)
+			trackedProcess = &lm.Process{ID: lastProcess.ID, AssemblyID: k8sInstance.Status.ID, IntentType: lastProcess.IntentType, Status: lastProcess.Status, StatusReason: lastProcess.StatusReason}
+			trackedProcessFound = true
 		} else {
-			sync.logger.Info("Latest process not found in LM")
+			trackedProcess, trackedProcessFound, stopSync = sync.getProcessByID(lastProcess.ID)
+			if stopSync {
+				return nil, stopSync
+			} else if !trackedProcessFound {
+				processLogger.Info("Process started by the operator not found in LM")
+			} else {
+				processLogger.Info("Process started by the operator found in LM", LogKeys.ProcessStatus, trackedProcess.Status)
+			}
 		}
+	}
 
+	if !trackedProcessFound {
+		// Fallback to the latest Process, only used to detect activity on the Assembly not started by the operator
+		latestProcess, latestProcessFound, stopSync = sync.getLatestProcess()
+		if stopSync {
+			return nil, stopSync
+		} else if !latestProcessFound {
+			sync.logger.Info("Latest process not found in LM")
+		} else if latestProcess.AssemblyID != assemblyInstance.ID {
+			//Process is for a previous Assembly with the same name
+			sync.logger.Info("Latest process not found in LM")
+			latestProcessFound = false
+		} else {
+			sync.logger.Info("Latest process found in LM", LogKeys.ProcessID, latestProcess.ID, LogKeys.ProcessStatus, latestProcess.Status)
+		}
 	}
 
 	return &LMSourceOfTruth{
 		assemblyInstance:      assemblyInstance,
 		assemblyInstanceFound: assemblyInstanceFound,
+		trackedProcess:        trackedProcess,
+		trackedProcessFound:   trackedProcessFound,
 		latestProcess:         latestProcess,
 		latestProcessFound:    latestProcessFound,
 	}, false
 }
 
 func (sync *AssemblySynchronizer) syncStatusWithLM() (stopSync bool) {
+	if stopSync := sync.resolveAssemblyName(); stopSync {
+		return stopSync
+	}
 	lmSourceOfTruth, stopSync := sync.fetchLMSourceOfTruth()
 	if stopSync {
 		return stopSync
@@ -278,7 +512,12 @@ func (sync *AssemblySynchronizer) syncStatusWithLM() (stopSync bool) {
 	sync.logger.Info("Syncing state with data from LM")
 	k8sInstance := sync.k8sInstance
 	assemblyInstance := lmSourceOfTruth.assemblyInstance
-	if !lmSourceOfTruth.assemblyInstanceFound {
+	conflict := sync.checkAdoption(lmSourceOfTruth)
+	if conflict != "" {
+		// Activity on the Assembly is not ours to follow
+		lmSourceOfTruth.latestProcess = nil
+		lmSourceOfTruth.latestProcessFound = false
+	} else if !lmSourceOfTruth.assemblyInstanceFound {
 		k8sInstance.Status.State = "NotFound"
 		k8sInstance.Status.Properties = make(map[string]string)
 	} else {
@@ -291,25 +530,65 @@ func (sync *AssemblySynchronizer) syncStatusWithLM() (stopSync bool) {
 		}
 		k8sInstance.Status.Properties = make(map[string]string)
 		for _, property := range assemblyInstance.Properties {
-			k8sInstance.Status.Properties[property.Name] = property.Value
+			k8sInstance.Status.Properties[property.Name] = sync.redactProperty(property.Name, property.Value)
 		}
 	}
 
-	latestProcess := lmSourceOfTruth.latestProcess
-
-	k8sInstance.Status.LastProcess = stratossv1alpha1.Process{Status: "None", IntentType: "None"}
-	if lmSourceOfTruth.latestProcessFound {
-		k8sInstance.Status.LastProcess.ID = latestProcess.ID
-		k8sInstance.Status.LastProcess.IntentType = translateIntentType(latestProcess.IntentType)
-		k8sInstance.Status.LastProcess.Status = latestProcess.Status
-		k8sInstance.Status.LastProcess.StatusReason = latestProcess.StatusReason
+	trackedProcess := lmSourceOfTruth.trackedProcess
+	if lmSourceOfTruth.trackedProcessFound {
+		previousProcessStatus := k8sInstance.Status.LastProcess.Status
+		if trackedProcess.IntentType != "" {
+			k8sInstance.Status.LastProcess.IntentType = translateIntentType(trackedProcess.IntentType)
+		}
+		k8sInstance.Status.LastProcess.Status = trackedProcess.Status
+		k8sInstance.Status.LastProcess.StatusReason = trackedProcess.StatusReason
+		if processIsOngoing(previousProcessStatus) && !processIsOngoing(trackedProcess.Status) {
+			sync.recordProcessOutcome()
+			sync.onIntentOutcome()
+		}
+	} else {
+		k8sInstance.Status.LastProcess = stratossv1alpha1.Process{Status: "None", IntentType: "None"}
 	}
 
+	if conflict != "" {
+		sync.onAdoptionConflict(conflict)
+	} else {
+		sync.adoptionConflict = ""
+		sync.updateDegradedCondition()
+	}
+	sync.updateSuspendedCondition()
+	sync.lmSourceOfTruth = lmSourceOfTruth
 	sync.needsStatusUpdate = true
-	//stopSync = sync.updateK8sInstanceStatus()
 	return false
 }
 
+func (sync *AssemblySynchronizer) recordProcessOutcome() {
+	lastProcess := sync.k8sInstance.Status.LastProcess
+	switch lastProcess.Status {
+	case stratossv1alpha1.ProcessStatus.Completed:
+		sync.recordNormalEvent(EventReasons.ProcessCompleted, "%s process %s completed", lastProcess.IntentType, lastProcess.ID)
+	case stratossv1alpha1.ProcessStatus.Failed:
+		sync.recordWarningEvent(EventReasons.ProcessFailed, "%s process %s failed: %s", lastProcess.IntentType, lastProcess.ID, lastProcess.StatusReason)
+	case stratossv1alpha1.ProcessStatus.Cancelled:
+		sync.recordWarningEvent(EventReasons.ProcessCancelled, "%s process %s was cancelled: %s", lastProcess.IntentType, lastProcess.ID, lastProcess.StatusReason)
+	}
+}
+
+func (sync *AssemblySynchronizer) updateDegradedCondition() {
+	k8sInstance := sync.k8sInstance
+	lastProcess := k8sInstance.Status.LastProcess
+	switch {
+	case k8sInstance.Status.State == stratossv1alpha1.AssemblyStates.Failed:
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionTrue, ConditionReasons.AssemblyFailed, "Assembly is in a Failed state in LM")
+	case k8sInstance.Status.State == stratossv1alpha1.AssemblyStates.Broken:
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionTrue, ConditionReasons.AssemblyBroken, "Assembly is in a Broken state in LM")
+	case lastProcess.Status == stratossv1alpha1.ProcessStatus.Failed:
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionTrue, ConditionReasons.ProcessFailed, fmt.Sprintf("%s process %s failed: %s", lastProcess.IntentType, lastProcess.ID, lastProcess.StatusReason))
+	default:
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionFalse, ConditionReasons.Healthy, "")
+	}
+}
+
 func translateIntentType(incomingIntentType string) (outputIntentType string) {
 	switch incomingIntentType {
 	case "ChangeAssemblyState":
@@ -340,8 +619,9 @@ func (sync *AssemblySynchronizer) checkForOngoingProcess() (stopSync bool) {
 		processLogger := sync.logger.WithValues(LogKeys.ProcessID, sync.k8sInstance.Status.LastProcess.ID)
 		if processIsOngoing(sync.k8sInstance.Status.LastProcess.Status) {
 			processLogger.Info("Process has not completed yet, will requeue reconcile", LogKeys.ProcessStatus, sync.k8sInstance.Status.LastProcess.Status)
+			setCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.Progressing, corev1.ConditionTrue, ConditionReasons.ProcessInProgress, fmt.Sprintf("%s process %s is %s", sync.k8sInstance.Status.LastProcess.IntentType, sync.k8sInstance.Status.LastProcess.ID, sync.k8sInstance.Status.LastProcess.Status))
 			sync.requeue = true
-			sync.requeueDelay = 5
+			sync.requeueDelay = sync.poller.nextPoll(sync.k8sInstance, sync.k8sInstance.Status.LastProcess.ID)
 			sync.stopSync = true
 			return sync.stopSync
 		} else {
@@ -350,39 +630,108 @@ func (sync *AssemblySynchronizer) checkForOngoingProcess() (stopSync bool) {
 	} else {
 		sync.logger.Info("No process associated to Assembly")
 	}
+	if sync.lmSourceOfTruth != nil && sync.lmSourceOfTruth.latestProcessFound {
+		latestProcess := sync.lmSourceOfTruth.latestProcess
+		if processIsOngoing(latestProcess.Status) {
+			sync.logger.Info("Assembly has a process, not started by the operator, which has not completed yet, will requeue reconcile", LogKeys.ProcessID, latestProcess.ID, LogKeys.ProcessStatus, latestProcess.Status)
+			setCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.Progressing, corev1.ConditionTrue, ConditionReasons.ProcessInProgress, fmt.Sprintf("%s process %s, not started by the operator, is %s", translateIntentType(latestProcess.IntentType), latestProcess.ID, latestProcess.Status))
+			sync.requeue = true
+			sync.requeueDelay = sync.poller.nextPoll(sync.k8sInstance, latestProcess.ID)
+			sync.stopSync = true
+			return sync.stopSync
+		}
+	}
+	sync.poller.forget(sync.k8sInstance)
+	if sync.k8sInstance.Status.LastProcess.ID != "" {
+		setCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.Progressing, corev1.ConditionFalse, ConditionReasons.ProcessComplete, fmt.Sprintf("%s process %s is %s", sync.k8sInstance.Status.LastProcess.IntentType, sync.k8sInstance.Status.LastProcess.ID, sync.k8sInstance.Status.LastProcess.Status))
+	} else {
+		setCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.Progressing, corev1.ConditionFalse, ConditionReasons.NoProcess, "")
+	}
 	return false
 }
 
+// onProcessStarted records the process started by an intent, so it is followed on subsequent reconciles until it completes
+func (sync *AssemblySynchronizer) onProcessStarted(processID string, intentType string) (stopSync bool) {
+	sync.k8sInstance.Status.LastProcess = stratossv1alpha1.Process{
+		ID:               processID,
+		IntentType:       intentType,
+		Status:           stratossv1alpha1.ProcessStatus.Pending,
+		DesiredStateHash: sync.desiredStateHash(),
+	}
+	setCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.Progressing, corev1.ConditionTrue, ConditionReasons.IntentRequested, fmt.Sprintf("%s requested, started process %s", intentType, processID))
+	sync.recordNormalEvent(EventReasons.IntentRequested, "%s requested, started process %s", intentType, processID)
+	sync.recordIntent()
+	sync.needsStatusUpdate = true
+	sync.newProcessStarted = true
+	// Requeue request to check progress, starting again from the initial polling interval
+	sync.requeue = true
+	sync.requeueDelay = sync.poller.nextPoll(sync.k8sInstance, processID)
+	sync.stopSync = true
+	return sync.stopSync
+}
+
 func (sync *AssemblySynchronizer) syncExistence() (stopSync bool) {
 	k8sInstance := sync.k8sInstance
 	isDeleting := k8sInstance.GetDeletionTimestamp() != nil
 	if isDeleting {
 		sync.logger.Info("Assembly (CR) has deletion timestamp")
 		if finalizerContains(k8sInstance.GetFinalizers(), assemblyFinalizer) {
-			if k8sInstance.Status.State == "NotFound" {
+			policy, err := deletionPolicy(k8sInstance)
+			if err != nil {
+				sync.logger.Error(err, "Invalid deletion policy, Assembly will not be deleted or orphaned")
+				return sync.onLMError(err)
+			}
+			if sync.adoptionConflict != "" {
+				sync.logger.Info("Assembly in LM is not managed by this resource, removing finalizer without deleting it")
+				k8sInstance.SetFinalizers(finalizerRemove(k8sInstance.GetFinalizers(), assemblyFinalizer))
+				sync.recordNormalEvent(EventReasons.FinalizerRemoved, "Assembly in LM is not managed by this resource, removed finalizer without deleting it")
+				sync.hasFinalizerChanges = true
+				sync.isDeleted = true
+				sync.stopSync = true
+				return sync.stopSync
+			} else if k8sInstance.Status.State == "NotFound" {
 				sync.logger.Info("Assembly no longer exists in LM, safe to remove finalizer and delete K8s instance")
 				k8sInstance.SetFinalizers(finalizerRemove(k8sInstance.GetFinalizers(), assemblyFinalizer))
+				sync.recordNormalEvent(EventReasons.FinalizerRemoved, "Assembly no longer exists in LM, removed finalizer")
+				sync.hasFinalizerChanges = true
+				sync.isDeleted = true
+				sync.stopSync = true
+				return sync.stopSync
+			} else if policy == stratossv1alpha1.DeletionPolicies.Orphan {
+				sync.logger.Info("Deletion policy is Orphan, removing finalizer without deleting Assembly from LM")
+				k8sInstance.SetFinalizers(finalizerRemove(k8sInstance.GetFinalizers(), assemblyFinalizer))
+				sync.recordNormalEvent(EventReasons.Orphaned, "Deletion policy is Orphan, Assembly %s was left running in LM", k8sInstance.Status.ID)
 				sync.hasFinalizerChanges = true
 				sync.isDeleted = true
 				sync.stopSync = true
 				return sync.stopSync
 			} else {
+				if stopSync := sync.checkRetryPolicy("Delete"); stopSync {
+					return stopSync
+				}
+				if stopSync := sync.checkDependents(); stopSync {
+					return stopSync
+				}
 				//Trigger delete
-				processID, err := sync.lmClient.DeleteAssembly(lm.DeleteAssemblyRequest{
-					AssemblyName: k8sInstance.Name,
-				})
+				request := lm.DeleteAssemblyRequest{
+					AssemblyName: sync.assemblyName(),
+				}
+				if plannedBy(k8sInstance) != "" {
+					return sync.planIntent("Delete", request)
+				}
+				if stopSync := sync.checkPlan("Delete"); stopSync {
+					return stopSync
+				}
+				if stopSync := sync.checkMaintenanceWindow("Delete"); stopSync {
+					return stopSync
+				}
+				processID, err := sync.lmClient.DeleteAssembly(request)
 				if err != nil {
 					sync.logger.Error(err, "Failed to request deletion of Assembly")
 					return sync.onLMError(err)
 				} else {
 					sync.logger.Info("Delete Assembly request accepted", LogKeys.ProcessID, processID)
-					sync.needsStatusUpdate = true
-					sync.newProcessStarted = true
-					// Requeue request to check progress
-					sync.requeue = true
-					sync.requeueDelay = 5
-					sync.stopSync = true
-					return sync.stopSync
+					return sync.onProcessStarted(processID, "Delete")
 				}
 			}
 		} else {
@@ -399,27 +748,46 @@ func (sync *AssemblySynchronizer) syncExistence() (stopSync bool) {
 			sync.hasFinalizerChanges = true
 		}
 
+		if sync.adoptionConflict != "" {
+			// Leave the Assembly untouched, checking again later in case the conflict is resolved in LM
+			sync.requeue = true
+			sync.requeueDelay = adoptionConflictRequeueDelay
+			sync.stopSync = true
+			return sync.stopSync
+		}
+
 		// Create if not found
 		if k8sInstance.Status.State == "NotFound" {
-			sync.logger.Info("Requesting creation of Assembly")
-			processID, err := sync.lmClient.CreateAssembly(lm.CreateAssemblyRequest{
-				AssemblyName:   k8sInstance.Name,
+			if stopSync := sync.checkRetryPolicy("Create"); stopSync {
+				return stopSync
+			}
+			if stopSync := sync.checkDependencies("Create"); stopSync {
+				return stopSync
+			}
+			request := lm.CreateAssemblyRequest{
+				AssemblyName:   sync.assemblyName(),
 				DescriptorName: k8sInstance.Spec.DescriptorName,
 				IntendedState:  k8sInstance.Spec.IntendedState,
-				Properties:     k8sInstance.Spec.Properties,
-			})
+				Properties:     sync.desiredProperties,
+			}
+			if plannedBy(k8sInstance) != "" {
+				return sync.planIntent("Create", request)
+			}
+			if stopSync := sync.checkPlan("Create"); stopSync {
+				return stopSync
+			}
+			if stopSync := sync.checkMaintenanceWindow("Create"); stopSync {
+				return stopSync
+			}
+			sync.logger.Info("Requesting creation of Assembly")
+			processID, err := sync.lmClient.CreateAssembly(request)
 			if err != nil {
 				sync.logger.Error(err, "Failed to request creation of Assembly")
 				return sync.onLMError(err)
 			} else {
 				sync.logger.Info("Create Assembly request accepted", LogKeys.ProcessID, processID)
-				sync.needsStatusUpdate = true
-				sync.newProcessStarted = true
-				// Requeue request to check progress
-				sync.requeue = true
-				sync.requeueDelay = 5
-				sync.stopSync = true
-				return sync.stopSync
+				sync.recordManagedProperties()
+				return sync.onProcessStarted(processID, "Create")
 			}
 		}
 	}
@@ -430,23 +798,36 @@ func (sync *AssemblySynchronizer) syncAssemblyState() (stopSync bool) {
 	k8sInstance := sync.k8sInstance
 	if k8sInstance.Status.State != k8sInstance.Spec.IntendedState {
 		//State change
-		sync.logger.Info("Requesting state change of Assembly", LogKeys.IntendedState, k8sInstance.Spec.IntendedState)
-		processID, err := sync.lmClient.ChangeAssemblyState(lm.ChangeAssemblyStateRequest{
-			AssemblyName:  k8sInstance.Name,
+		if stopSync := sync.checkRetryPolicy("ChangeState"); stopSync {
+			return stopSync
+		}
+		if stopSync := sync.checkDependencies("ChangeState"); stopSync {
+			return stopSync
+		}
+		request := lm.ChangeAssemblyStateRequest{
+			AssemblyName:  sync.assemblyName(),
 			IntendedState: k8sInstance.Spec.IntendedState,
-		})
+		}
+		if plannedBy(k8sInstance) != "" {
+			return sync.planIntent("ChangeState", request)
+		}
+		if stopSync := sync.checkPlan("ChangeState"); stopSync {
+			return stopSync
+		}
+		if stopSync := sync.checkApproval("ChangeState", stateChangeClasses(k8sInstance.Spec.IntendedState)...); stopSync {
+			return stopSync
+		}
+		if stopSync := sync.checkMaintenanceWindow("ChangeState"); stopSync {
+			return stopSync
+		}
+		sync.logger.Info("Requesting state change of Assembly", LogKeys.IntendedState, k8sInstance.Spec.IntendedState)
+		processID, err := sync.lmClient.ChangeAssemblyState(request)
 		if err != nil {
 			sync.logger.Error(err, "Failed to request change state for Assembly")
 			return sync.onLMError(err)
 		} else {
 			sync.logger.Info("Change Assembly state request accepted", LogKeys.ProcessID, processID, LogKeys.IntendedState, k8sInstance.Spec.IntendedState)
-			sync.needsStatusUpdate = true
-			sync.newProcessStarted = true
-			// Requeue request to check progress
-			sync.requeue = true
-			sync.requeueDelay = 5
-			sync.stopSync = true
-			return sync.stopSync
+			return sync.onProcessStarted(processID, "ChangeState")
 		}
 	}
 	return false
@@ -459,36 +840,50 @@ func (sync *AssemblySynchronizer) syncAssemblyUpdateableState() (stopSync bool)
 		sync.logger.Info("Desired Assembly descriptorName differs from current state")
 		hasDifference = true
 	}
-	if !hasDifference {
-		for propName, specPropValue := range k8sInstance.Spec.Properties {
-			statusPropValue := k8sInstance.Status.Properties[propName]
-			if statusPropValue != specPropValue {
-				sync.logger.Info("Desired Assembly property values differ from current state", LogKeys.PropertyName, propName, LogKeys.DesiredPropertyValue, specPropValue, LogKeys.ObservedPropertyValue, statusPropValue)
-				hasDifference = true
-				break
-			}
-		}
+	changes := sync.computePropertyChanges()
+	for _, change := range changes {
+		sync.logger.Info("Desired Assembly property values differ from current state", LogKeys.PropertyName, change.Name, LogKeys.PropertyChange, change.Change, LogKeys.DesiredPropertyValue, change.DesiredValue, LogKeys.ObservedPropertyValue, change.ObservedValue)
+		hasDifference = true
+	}
+	if len(changes) > 0 || len(k8sInstance.Status.PendingChanges) > 0 {
+		k8sInstance.Status.PendingChanges = changes
+		sync.needsStatusUpdate = true
 	}
 	if hasDifference {
 		// Upgrade
-		sync.logger.Info("Requesting update of Assembly")
-		processID, err := sync.lmClient.UpgradeAssembly(lm.UpgradeAssemblyRequest{
-			AssemblyName:   k8sInstance.Name,
+		if stopSync := sync.checkRetryPolicy("Update"); stopSync {
+			return stopSync
+		}
+		request := lm.UpgradeAssemblyRequest{
+			AssemblyName:   sync.assemblyName(),
 			DescriptorName: k8sInstance.Spec.DescriptorName,
-			Properties:     k8sInstance.Spec.Properties,
-		})
+			Properties:     sync.desiredProperties,
+		}
+		if plannedBy(k8sInstance) != "" {
+			return sync.planIntent("Update", request)
+		}
+		if stopSync := sync.checkPlan("Update"); stopSync {
+			return stopSync
+		}
+		changeClass := stratossv1alpha1.ChangeClasses.PropertyChange
+		if k8sInstance.Status.DescriptorName != k8sInstance.Spec.DescriptorName {
+			changeClass = stratossv1alpha1.ChangeClasses.DescriptorChange
+		}
+		if stopSync := sync.checkApproval("Update", changeClass); stopSync {
+			return stopSync
+		}
+		if stopSync := sync.checkMaintenanceWindow("Update"); stopSync {
+			return stopSync
+		}
+		sync.logger.Info("Requesting update of Assembly")
+		processID, err := sync.lmClient.UpgradeAssembly(request)
 		if err != nil {
 			sync.logger.Error(err, "Failed to request update for Assembly")
 			return sync.onLMError(err)
 		} else {
 			sync.logger.Info("Update Assembly request accepted", LogKeys.ProcessID, processID)
-			sync.needsStatusUpdate = true
-			sync.newProcessStarted = true
-			// Requeue request to check progress
-			sync.requeue = true
-			sync.requeueDelay = 5
-			sync.stopSync = true
-			return sync.stopSync
+			sync.recordManagedProperties()
+			return sync.onProcessStarted(processID, "Update")
 		}
 	}
 	return false
@@ -526,30 +921,36 @@ func (sync *AssemblySynchronizer) endReconcile() (reconcile.Result, error) {
 		sync.k8sInstance.Status.SyncState.Error = errStr
 		sync.needsStatusUpdate = true
 		if errStr == previousError {
-			//Same error as last time
+			//Same error as last time, already reported with an event
 			sync.k8sInstance.Status.SyncState.Attempts = previousAttempts + 1
 		} else {
 			//Different error
 			sync.k8sInstance.Status.SyncState.Attempts = 1
+			sync.recordWarningEvent(EventReasons.SyncError, "Failed to synchronize Assembly with LM: %s", errStr)
 		}
 	} else if previousStatus != "OK" {
 		// No errors, only update if the last sync status reported an error
 		sync.needsStatusUpdate = true
 	}
+	if !sync.isDeleted {
+		sync.recordSyncBookkeeping(lastError)
+		sync.updateSyncedAndReadyConditions(lastError)
+	}
 
-	//Now update the K8s instance with all the changes from this reconcile
+	//Now patch the K8s instance with all the changes from this reconcile
 	updateReportedStop := false
-	//Make a copy of the finalizers now incase update returns data from server
-	finalizers := make([]string, len(sync.k8sInstance.GetFinalizers()))
-	copy(finalizers, sync.k8sInstance.GetFinalizers())
 	if sync.needsStatusUpdate && !sync.isDeleted {
-		sync.logger.Info("Updating Assembly (CR) status")
-		updateReportedStop = sync.updateK8sInstanceStatus()
+		sync.logger.Info("Patching Assembly (CR) status")
+		updateReportedStop = sync.patchK8sInstanceStatus()
 	}
 	if sync.hasFinalizerChanges {
-		sync.logger.Info("Updating Assembly (CR) instance finalizers")
-		sync.k8sInstance.SetFinalizers(finalizers)
-		updateInstanceReportedStop := sync.updateK8sInstance()
+		sync.logger.Info("Patching Assembly (CR) finalizers")
+		updateInstanceReportedStop := sync.patchK8sInstanceFinalizers(finalizerContains(sync.k8sInstance.GetFinalizers(), assemblyFinalizer))
+		updateReportedStop = updateReportedStop || updateInstanceReportedStop
+	}
+	if sync.approval != "" && sync.newProcessStarted && !sync.isDeleted {
+		sync.logger.Info("Removing approval annotation from Assembly (CR)")
+		updateInstanceReportedStop := sync.removeK8sInstanceApproval()
 		updateReportedStop = updateReportedStop || updateInstanceReportedStop
 	}
 
@@ -562,11 +963,51 @@ func (sync *AssemblySynchronizer) endReconcile() (reconcile.Result, error) {
 		}
 	}
 
-	res := reconcile.Result{Requeue: sync.requeue, RequeueAfter: time.Duration(sync.requeueDelay) * time.Second}
+	if sync.isDeleted {
+		sync.poller.forget(sync.k8sInstance)
+	} else if !sync.requeue && lastError == nil {
+		// Nothing in progress, check again later for changes made outside of the operator
+		sync.requeueDelay = sync.poller.resync(sync.k8sInstance)
+		// and to move the maintenance window on the status on once it opens or closes
+		if untilWindowChanges, found := untilMaintenanceWindowChanges(sync.k8sInstance, time.Now()); found && untilWindowChanges > 0 &&
+			(sync.requeueDelay == 0 || untilWindowChanges < sync.requeueDelay) {
+			sync.requeueDelay = untilWindowChanges
+		}
+	}
+
+	res := reconcile.Result{Requeue: sync.requeue, RequeueAfter: sync.requeueDelay}
 	sync.logger.Info(fmt.Sprintf("Reconcile result: %+v, Reconcile error: %+v", res, lastError))
 	return res, lastError
 }
 
+func (sync *AssemblySynchronizer) updateSyncedAndReadyConditions(lastError error) {
+	k8sInstance := sync.k8sInstance
+	if lastError != nil {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Synced, corev1.ConditionFalse, ConditionReasons.SyncError, lastError.Error())
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, ConditionReasons.SyncError, "Unable to synchronize the Assembly with LM")
+		return
+	}
+	setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Synced, corev1.ConditionTrue, ConditionReasons.Synced, "")
+
+	if progressing := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Progressing); progressing != nil && progressing.Status == corev1.ConditionTrue {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, progressing.Reason, progressing.Message)
+	} else if waiting := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.WaitingForDependencies); waiting != nil && waiting.Status == corev1.ConditionTrue {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, waiting.Reason, waiting.Message)
+	} else if pending := k8sInstance.Status.PendingApproval; pending != nil {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, ConditionReasons.AwaitingApproval, fmt.Sprintf("%s (%s) is waiting for approval of spec hash %s", pending.IntentType, pending.ChangeClass, pending.SpecHash))
+	} else if sync.planOutdated != "" {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, ConditionReasons.PlanOutdated, sync.planOutdated)
+	} else if sync.intentDeferred != "" {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, ConditionReasons.MaintenanceWindowClosed, sync.intentDeferred)
+	} else if degraded := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded); degraded != nil && degraded.Status == corev1.ConditionTrue {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, degraded.Reason, degraded.Message)
+	} else if k8sInstance.Status.State != k8sInstance.Spec.IntendedState || k8sInstance.Status.DescriptorName != k8sInstance.Spec.DescriptorName {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionFalse, ConditionReasons.NotReady, fmt.Sprintf("Assembly is %s with descriptor %s, waiting to be %s with descriptor %s", k8sInstance.Status.State, k8sInstance.Status.DescriptorName, k8sInstance.Spec.IntendedState, k8sInstance.Spec.DescriptorName))
+	} else {
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready, corev1.ConditionTrue, ConditionReasons.Ready, fmt.Sprintf("Assembly is %s", k8sInstance.Status.State))
+	}
+}
+
 // Reconcile reads that state of the cluster for a Assembly object and makes changes based on the state read
 // and what is in the Assembly.Spec
 // Note:
@@ -594,21 +1035,56 @@ func (r *AssemblyReconciler) Reconcile(request reconcile.Request) (reconcile.Res
 
 	sync := &AssemblySynchronizer{
 		k8sClient:        r.k8sClient,
+		apiReader:        r.apiReader,
+		scheme:           r.scheme,
 		k8sInstance:      instance,
-		lmClient:         *r.lmClient,
+		original:         instance.DeepCopy(),
+		lmClient:         r.lmClient,
+		recorder:         r.recorder,
+		namer:            r.namer,
+		poller:           r.poller,
 		logger:           syncLogger,
 		reconcileRequest: request,
 		stopSync:         false,
 		requeue:          false,
 	}
 
+	if remaining, unchanged := sync.unchangedSinceLastSync(); unchanged {
+		syncLogger.Info("Nothing has changed since the last successful sync, skipping reconcile")
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
 	if stopSync := sync.syncStatusWithLM(); stopSync {
 		return sync.endReconcile()
 	}
 
+	if stopSync := sync.publishOutputs(); stopSync {
+		return sync.endReconcile()
+	}
+
 	if stopSync := sync.checkForOngoingProcess(); stopSync {
 		return sync.endReconcile()
 	}
+	sync.specObserved = true
+
+	if stopSync := sync.checkSuspended(); stopSync {
+		return sync.endReconcile()
+	}
+
+	if stopSync := sync.resolveProperties(); stopSync {
+		return sync.endReconcile()
+	}
+	sync.clearFailedIntents()
+	sync.pruneManagedProperties()
+	sync.resolveMaintenanceWindow()
+
+	if stopSync := sync.validateDependsOn(); stopSync {
+		return sync.endReconcile()
+	}
+
+	if stopSync := sync.checkDrift(); stopSync {
+		return sync.endReconcile()
+	}
 
 	if stopSync := sync.syncExistence(); stopSync {
 		return sync.endReconcile()
@@ -622,6 +1098,8 @@ func (r *AssemblyReconciler) Reconcile(request reconcile.Request) (reconcile.Res
 		return sync.endReconcile()
 	}
 
+	sync.inSync = true
+	sync.clearWaitingCondition()
 	return sync.endReconcile()
 }
 
@@ -0,0 +1,461 @@
+package assembly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const testNamespace = "test"
+
+var testPollSettings = pollSettings{
+	initialInterval: time.Second,
+	maxInterval:     8 * time.Second,
+	jitter:          0.2,
+	resyncPeriod:    10 * time.Minute,
+}
+
+// testEnv runs an AssemblyReconciler against a fake Kubernetes client and a fake LM server
+type testEnv struct {
+	t          *testing.T
+	lm         *fake.Server
+	lmClient   *lm.LMRestClient
+	k8sClient  client.Client
+	recorder   *record.FakeRecorder
+	reconciler *AssemblyReconciler
+}
+
+func newTestEnv(t *testing.T, objs ...runtime.Object) *testEnv {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to build scheme: %s", err)
+	}
+	if err := stratossv1alpha1.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to build scheme: %s", err)
+	}
+	server := fake.NewServer()
+	lmClient, err := lm.BuildClient(server.Configuration())
+	if err != nil {
+		t.Fatalf("Failed to build LM client: %s", err)
+	}
+	namer, err := buildAssemblyNamer(NamingStrategies.Plain, "")
+	if err != nil {
+		t.Fatalf("Failed to build namer: %s", err)
+	}
+	k8sClient := fakeclient.NewFakeClientWithScheme(scheme, objs...)
+	recorder := record.NewFakeRecorder(1000)
+	return &testEnv{
+		t:         t,
+		lm:        server,
+		lmClient:  lmClient,
+		k8sClient: k8sClient,
+		recorder:  recorder,
+		reconciler: &AssemblyReconciler{
+			k8sClient: k8sClient,
+			apiReader: k8sClient,
+			scheme:    scheme,
+			lmClient:  lmClient,
+			recorder:  recorder,
+			namer:     namer,
+			poller:    newProcessPoller(testPollSettings),
+		},
+	}
+}
+
+func (env *testEnv) close() {
+	env.lmClient.Close()
+	env.lm.Close()
+}
+
+// completeImmediately makes processes of every intent type complete as soon as they are started
+func (env *testEnv) completeImmediately() {
+	for _, intentType := range []string{fake.CreateAssemblyIntent, fake.UpgradeAssemblyIntent, fake.ChangeAssemblyStateIntent, fake.DeleteAssemblyIntent} {
+		env.lm.SetProcessLifecycle(intentType, fake.ProcessLifecycle{})
+	}
+}
+
+func newTestAssembly(name string, spec stratossv1alpha1.AssemblySpec) *stratossv1alpha1.Assembly {
+	return &stratossv1alpha1.Assembly{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  testNamespace,
+			Name:       name,
+			UID:        types.UID("uid-" + name),
+			Generation: 1,
+		},
+		Spec: spec,
+	}
+}
+
+func activeSpec() stratossv1alpha1.AssemblySpec {
+	return stratossv1alpha1.AssemblySpec{
+		DescriptorName: "assembly::test::1.0",
+		IntendedState:  stratossv1alpha1.AssemblyStates.Active,
+		Properties:     map[string]string{"size": "small"},
+	}
+}
+
+func (env *testEnv) reconcile(name string) (reconcile.Result, error) {
+	return env.reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}})
+}
+
+// mustReconcile reconciles the Assembly, failing the test if the reconcile returns an error
+func (env *testEnv) mustReconcile(name string) reconcile.Result {
+	env.t.Helper()
+	result, err := env.reconcile(name)
+	if err != nil {
+		env.t.Fatalf("Reconcile of %s failed: %s", name, err)
+	}
+	return result
+}
+
+// reconcileUntil reconciles the Assembly until done returns true, failing the test if it takes more than 10 reconciles
+func (env *testEnv) reconcileUntil(name string, done func(instance *stratossv1alpha1.Assembly) bool) {
+	env.t.Helper()
+	for i := 0; i < 10; i++ {
+		env.reconcile(name)
+		if done(env.get(name)) {
+			return
+		}
+	}
+	env.t.Fatalf("Assembly %s did not reach the expected status, status is %+v", name, env.get(name).Status)
+}
+
+func (env *testEnv) get(name string) *stratossv1alpha1.Assembly {
+	env.t.Helper()
+	instance := &stratossv1alpha1.Assembly{}
+	if err := env.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: name}, instance); err != nil {
+		env.t.Fatalf("Failed to get Assembly %s: %s", name, err)
+	}
+	return instance
+}
+
+// edit changes the Assembly as a user would, moving the generation on when the spec changes
+func (env *testEnv) edit(name string, change func(instance *stratossv1alpha1.Assembly)) {
+	env.t.Helper()
+	instance := env.get(name)
+	spec := instance.Spec.DeepCopy()
+	change(instance)
+	if specHash(*spec) != specHash(instance.Spec) {
+		instance.Generation++
+	}
+	if err := env.k8sClient.Update(context.TODO(), instance); err != nil {
+		env.t.Fatalf("Failed to update Assembly %s: %s", name, err)
+	}
+}
+
+func (env *testEnv) markDeleted(name string) {
+	env.edit(name, func(instance *stratossv1alpha1.Assembly) {
+		now := metav1.Now()
+		instance.SetDeletionTimestamp(&now)
+	})
+}
+
+// events returns, and clears, the events recorded so far
+func (env *testEnv) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-env.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func hasEvent(events []string, reason string) bool {
+	for _, event := range events {
+		if strings.Contains(event, " "+reason+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func conditionStatus(instance *stratossv1alpha1.Assembly, conditionType string) (corev1.ConditionStatus, string) {
+	condition := findCondition(instance, conditionType)
+	if condition == nil {
+		return "", ""
+	}
+	return condition.Status, condition.Reason
+}
+
+func isActive(instance *stratossv1alpha1.Assembly) bool {
+	return instance.Status.State == stratossv1alpha1.AssemblyStates.Active && !processIsOngoing(instance.Status.LastProcess.Status)
+}
+
+func TestReconcileCreatesAssembly(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+
+	result := env.mustReconcile("a1")
+	if !result.Requeue || result.RequeueAfter <= 0 {
+		t.Errorf("Expected the reconcile to be requeued to follow the process but was %+v", result)
+	}
+	instance := env.get("a1")
+	if !finalizerContains(instance.GetFinalizers(), assemblyFinalizer) {
+		t.Errorf("Expected the finalizer to be added but finalizers are %v", instance.GetFinalizers())
+	}
+	if instance.Status.LastProcess.IntentType != "Create" || instance.Status.LastProcess.ID == "" {
+		t.Errorf("Expected the Create process to be recorded but was %+v", instance.Status.LastProcess)
+	}
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Progressing); status != corev1.ConditionTrue {
+		t.Errorf("Expected Progressing to be True but was %s (%s)", status, reason)
+	}
+	requests := env.lm.IntentRequests("createAssembly")
+	if len(requests) != 1 || !strings.Contains(requests[0].Body, `"size":"small"`) {
+		t.Fatalf("Expected a single createAssembly request with the properties but found %v", requests)
+	}
+	if events := env.events(); !hasEvent(events, EventReasons.IntentRequested) {
+		t.Errorf("Expected an %s event but found %v", EventReasons.IntentRequested, events)
+	}
+
+	env.reconcileUntil("a1", isActive)
+	instance = env.get("a1")
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Ready); status != corev1.ConditionTrue {
+		t.Errorf("Expected Ready to be True but was %s (%s)", status, reason)
+	}
+	if instance.Status.Properties["size"] != "small" {
+		t.Errorf("Expected the properties in LM to be reported on the status but were %v", instance.Status.Properties)
+	}
+	if events := env.events(); !hasEvent(events, EventReasons.ProcessCompleted) {
+		t.Errorf("Expected a %s event but found %v", EventReasons.ProcessCompleted, events)
+	}
+	if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 1 {
+		t.Errorf("Expected no further createAssembly requests but found %d", len(requests))
+	}
+}
+
+func TestReconcileUpdatesAssembly(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(spec *stratossv1alpha1.AssemblySpec)
+		intent string
+		check  func(assembly lm.Assembly) bool
+	}{
+		{
+			name:   "property changed",
+			change: func(spec *stratossv1alpha1.AssemblySpec) { spec.Properties["size"] = "large" },
+			intent: "upgradeAssembly",
+			check: func(assembly lm.Assembly) bool {
+				return len(assembly.Properties) == 1 && assembly.Properties[0].Value == "large"
+			},
+		},
+		{
+			name:   "descriptor changed",
+			change: func(spec *stratossv1alpha1.AssemblySpec) { spec.DescriptorName = "assembly::test::2.0" },
+			intent: "upgradeAssembly",
+			check:  func(assembly lm.Assembly) bool { return assembly.DescriptorName == "assembly::test::2.0" },
+		},
+		{
+			name: "state changed",
+			change: func(spec *stratossv1alpha1.AssemblySpec) {
+				spec.IntendedState = stratossv1alpha1.AssemblyStates.Inactive
+			},
+			intent: "changeAssemblyState",
+			check:  func(assembly lm.Assembly) bool { return assembly.State == stratossv1alpha1.AssemblyStates.Inactive },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", isActive)
+
+			env.edit("a1", func(instance *stratossv1alpha1.Assembly) { tt.change(&instance.Spec) })
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				ready := findCondition(instance, stratossv1alpha1.ConditionTypes.Ready)
+				return ready != nil && ready.Status == corev1.ConditionTrue && ready.ObservedGeneration == 2
+			})
+			if requests := env.lm.IntentRequests(tt.intent); len(requests) != 1 {
+				t.Errorf("Expected a single %s request but found %d", tt.intent, len(requests))
+			}
+			assembly, _ := env.lm.GetAssembly("a1")
+			if !tt.check(assembly) {
+				t.Errorf("Assembly in LM was not changed as expected: %+v", assembly)
+			}
+		})
+	}
+}
+
+func TestReconcileDeletesAssembly(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+
+	env.markDeleted("a1")
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+	})
+	if requests := env.lm.IntentRequests("deleteAssembly"); len(requests) != 1 {
+		t.Errorf("Expected a single deleteAssembly request but found %d", len(requests))
+	}
+	if _, found := env.lm.GetAssembly("a1"); found {
+		t.Errorf("Expected the Assembly to be deleted from LM")
+	}
+	if events := env.events(); !hasEvent(events, EventReasons.FinalizerRemoved) {
+		t.Errorf("Expected a %s event but found %v", EventReasons.FinalizerRemoved, events)
+	}
+}
+
+func TestReconcileReportsLMErrors(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.lm.InjectError(http.MethodPost, "/api/intent/createAssembly", http.StatusInternalServerError, 2)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if _, err := env.reconcile("a1"); err == nil {
+			t.Fatalf("Expected the reconcile to return the LM error")
+		}
+		instance := env.get("a1")
+		if instance.Status.SyncState.Status != stateError || instance.Status.SyncState.Attempts != attempt {
+			t.Errorf("Expected sync state %s after %d attempt(s) but was %+v", stateError, attempt, instance.Status.SyncState)
+		}
+		if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+			t.Errorf("Expected Synced to be False (%s) but was %s (%s)", ConditionReasons.SyncError, status, reason)
+		}
+	}
+	// The same error is only reported once
+	events := env.events()
+	syncErrors := 0
+	for _, event := range events {
+		if strings.Contains(event, EventReasons.SyncError) {
+			syncErrors++
+		}
+	}
+	if syncErrors != 1 {
+		t.Errorf("Expected a single %s event but found %v", EventReasons.SyncError, events)
+	}
+
+	env.reconcileUntil("a1", isActive)
+	if instance := env.get("a1"); instance.Status.SyncState.Status != "OK" {
+		t.Errorf("Expected sync state to recover but was %+v", instance.Status.SyncState)
+	}
+}
+
+func TestReconcileFollowsProcessStartedByIntent(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.lm.SetProcessLifecycle(fake.CreateAssemblyIntent, fake.ProcessLifecycle{Steps: []string{"In Progress"}, FinalStatus: "Failed", StatusReason: "boom"})
+
+	env.mustReconcile("a1")
+	processID := env.get("a1").Status.LastProcess.ID
+	env.mustReconcile("a1")
+	env.mustReconcile("a1")
+	if process, _ := env.lm.GetProcess(processID); process.Status != stratossv1alpha1.ProcessStatus.Failed {
+		t.Fatalf("Expected the process to have failed but was %s", process.Status)
+	}
+	events := env.events()
+	if !hasEvent(events, EventReasons.ProcessFailed) {
+		t.Errorf("Expected a %s event but found %v", EventReasons.ProcessFailed, events)
+	}
+	failedIntent := findFailedIntent(env.get("a1"), "Create")
+	if failedIntent == nil || failedIntent.Failures != 1 {
+		t.Errorf("Expected the failure of the Create intent to be recorded but failed intents are %+v", env.get("a1").Status.FailedIntents)
+	}
+}
+
+// staleCacheClient serves reads of Assemblies from a copy taken before the reconcile, as a cache which has not caught up would,
+// and changes the spec, as a user would, just before the reconcile writes the status. Unlike the fake client, it rejects a patch
+// made against a resourceVersion which is out of date, as the apiserver does
+type staleCacheClient struct {
+	client.Client
+	cached       map[types.NamespacedName]*stratossv1alpha1.Assembly
+	beforeStatus func()
+}
+
+func (c *staleCacheClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if instance, ok := obj.(*stratossv1alpha1.Assembly); ok && c.cached[key] != nil {
+		c.cached[key].DeepCopyInto(instance)
+		return nil
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *staleCacheClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if instance, ok := obj.(*stratossv1alpha1.Assembly); ok {
+		data, err := patch.Data(obj)
+		if err != nil {
+			return err
+		}
+		var precondition struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(data, &precondition); err != nil {
+			return err
+		}
+		current := &stratossv1alpha1.Assembly{}
+		if err := c.Client.Get(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, current); err != nil {
+			return err
+		}
+		if precondition.Metadata.ResourceVersion != "" && precondition.Metadata.ResourceVersion != current.GetResourceVersion() {
+			return errors.NewConflict(stratossv1alpha1.SchemeGroupVersion.WithResource("assemblies").GroupResource(), instance.Name, fmt.Errorf("the object has been modified"))
+		}
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *staleCacheClient) Status() client.StatusWriter {
+	if c.beforeStatus != nil {
+		c.beforeStatus()
+		c.beforeStatus = nil
+	}
+	return c.Client.Status()
+}
+
+func TestReconcileKeepsSpecChangedDuringReconcile(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.completeImmediately()
+	// Written once, so it has a resourceVersion as it would when read from the apiserver
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {})
+	key := types.NamespacedName{Namespace: testNamespace, Name: "a1"}
+	env.reconciler.k8sClient = &staleCacheClient{
+		Client: env.k8sClient,
+		cached: map[types.NamespacedName]*stratossv1alpha1.Assembly{key: env.get("a1")},
+		beforeStatus: func() {
+			env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "large" })
+		},
+	}
+
+	// Adds the finalizer, which is written after the status and so after the change to the spec
+	if _, err := env.reconcile("a1"); err != nil {
+		t.Fatalf("Expected a change to the spec during the reconcile not to fail it but was %s", err)
+	}
+	instance := env.get("a1")
+	if instance.Spec.Properties["size"] != "large" || instance.GetGeneration() != 2 {
+		t.Errorf("Expected the change to the spec to be kept but spec was %+v at generation %d", instance.Spec, instance.GetGeneration())
+	}
+	if !finalizerContains(instance.GetFinalizers(), assemblyFinalizer) {
+		t.Errorf("Expected the finalizer to be added to the changed Assembly")
+	}
+	if instance.Status.ObservedGeneration == instance.GetGeneration() {
+		t.Errorf("Expected the change to the spec not to be marked as observed by the reconcile which started before it")
+	}
+
+	env.reconciler.k8sClient = env.k8sClient
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.Properties["size"] == "large" && isActive(instance)
+	})
+}
@@ -0,0 +1,93 @@
+package assembly
+
+import (
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type conditionReasons struct {
+	Ready                    string
+	NotReady                 string
+	Synced                   string
+	SyncError                string
+	IntentRequested          string
+	ProcessInProgress        string
+	ProcessComplete          string
+	NoProcess                string
+	AssemblyFailed           string
+	AssemblyBroken           string
+	ProcessFailed            string
+	Healthy                  string
+	AdoptionConflict         string
+	Suspended                string
+	NotSuspended             string
+	RetryScheduled           string
+	RetriesExhausted         string
+	NoDrift                  string
+	DriftDetected            string
+	DriftReverted            string
+	DriftIgnored             string
+	DependenciesNotSatisfied string
+	DependenciesSatisfied    string
+	DependentsExist          string
+	AwaitingApproval         string
+	MaintenanceWindowClosed  string
+	PlanOutdated             string
+}
+
+var ConditionReasons = &conditionReasons{
+	Ready:                    "Ready",
+	NotReady:                 "NotReady",
+	Synced:                   "Synced",
+	SyncError:                "SyncError",
+	IntentRequested:          "IntentRequested",
+	ProcessInProgress:        "ProcessInProgress",
+	ProcessComplete:          "ProcessComplete",
+	NoProcess:                "NoProcess",
+	AssemblyFailed:           "AssemblyFailed",
+	AssemblyBroken:           "AssemblyBroken",
+	ProcessFailed:            "ProcessFailed",
+	Healthy:                  "Healthy",
+	AdoptionConflict:         "AdoptionConflict",
+	Suspended:                "Suspended",
+	NotSuspended:             "NotSuspended",
+	RetryScheduled:           "RetryScheduled",
+	RetriesExhausted:         "RetriesExhausted",
+	NoDrift:                  "NoDrift",
+	DriftDetected:            "DriftDetected",
+	DriftReverted:            "DriftReverted",
+	DriftIgnored:             "DriftIgnored",
+	DependenciesNotSatisfied: "DependenciesNotSatisfied",
+	DependenciesSatisfied:    "DependenciesSatisfied",
+	DependentsExist:          "DependentsExist",
+	AwaitingApproval:         "AwaitingApproval",
+	MaintenanceWindowClosed:  "MaintenanceWindowClosed",
+	PlanOutdated:             "PlanOutdated",
+}
+
+// findCondition returns the condition of the given type, or nil if it has not been set
+func findCondition(instance *stratossv1alpha1.Assembly, conditionType string) *stratossv1alpha1.Condition {
+	for i := range instance.Status.Conditions {
+		if instance.Status.Conditions[i].Type == conditionType {
+			return &instance.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition adds or updates the condition of the given type. The LastTransitionTime only moves when the status changes
+func setCondition(instance *stratossv1alpha1.Assembly, conditionType string, status corev1.ConditionStatus, reason string, message string) {
+	condition := findCondition(instance, conditionType)
+	if condition == nil {
+		instance.Status.Conditions = append(instance.Status.Conditions, stratossv1alpha1.Condition{Type: conditionType})
+		condition = &instance.Status.Conditions[len(instance.Status.Conditions)-1]
+	}
+	if condition.Status != status || condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Status = status
+	condition.Reason = reason
+	condition.Message = message
+	condition.ObservedGeneration = instance.GetGeneration()
+}
@@ -0,0 +1,221 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func dependencyKey(instance *stratossv1alpha1.Assembly, dependency stratossv1alpha1.AssemblyDependency) types.NamespacedName {
+	namespace := dependency.Namespace
+	if namespace == "" {
+		namespace = instance.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: dependency.Name}
+}
+
+func dependencyState(dependency stratossv1alpha1.AssemblyDependency) string {
+	if dependency.State == "" {
+		return stratossv1alpha1.AssemblyStates.Active
+	}
+	return dependency.State
+}
+
+// validateDependsOn rejects a dependency in another namespace, as the operator only caches and has permission to read Assemblies in the
+// namespace it watches. Not checked on deletion, which does not wait for the Assemblies this one depends on
+func (sync *AssemblySynchronizer) validateDependsOn() (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	if k8sInstance.GetDeletionTimestamp() != nil {
+		return false
+	}
+	for i, dependency := range k8sInstance.Spec.DependsOn {
+		if dependency.Namespace != "" && dependency.Namespace != k8sInstance.Namespace {
+			err := fmt.Errorf("spec.dependsOn[%d].namespace must be the namespace of the Assembly (%s) but was %s, dependencies in other namespaces are not supported", i, k8sInstance.Namespace, dependency.Namespace)
+			sync.logger.Error(err, "Invalid Assembly dependency")
+			return sync.onLMError(err)
+		}
+	}
+	return false
+}
+
+// dependsOn returns true if the Assembly depends on the Assembly with the given key
+func dependsOn(instance *stratossv1alpha1.Assembly, key types.NamespacedName) bool {
+	for _, dependency := range instance.Spec.DependsOn {
+		if dependencyKey(instance, dependency) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyCycle follows dependsOn from this Assembly through the Assemblies it depends on, returning the chain which leads back to this
+// Assembly if there is one. Such an Assembly could never be created, as each Assembly in the chain would wait for the next
+func (sync *AssemblySynchronizer) dependencyCycle() ([]string, error) {
+	k8sInstance := sync.k8sInstance
+	self := types.NamespacedName{Namespace: k8sInstance.Namespace, Name: k8sInstance.Name}
+	visited := map[types.NamespacedName]bool{self: true}
+	var walk func(instance *stratossv1alpha1.Assembly, chain []string) ([]string, error)
+	walk = func(instance *stratossv1alpha1.Assembly, chain []string) ([]string, error) {
+		for _, dependency := range instance.Spec.DependsOn {
+			key := dependencyKey(instance, dependency)
+			next := append(append([]string(nil), chain...), key.String())
+			if key == self {
+				return next, nil
+			}
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			dependencyInstance := &stratossv1alpha1.Assembly{}
+			if err := sync.k8sClient.Get(context.TODO(), key, dependencyInstance); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			if cycle, err := walk(dependencyInstance, next); err != nil || cycle != nil {
+				return cycle, err
+			}
+		}
+		return nil, nil
+	}
+	return walk(k8sInstance, []string{self.String()})
+}
+
+// unsatisfiedDependencies describes each Assembly this one depends on which is not yet in its required state
+func (sync *AssemblySynchronizer) unsatisfiedDependencies() ([]string, error) {
+	k8sInstance := sync.k8sInstance
+	cycle, err := sync.dependencyCycle()
+	if err != nil {
+		return nil, err
+	}
+	if cycle != nil {
+		return nil, fmt.Errorf("spec.dependsOn forms a cycle, so the Assembly can never be created: %s", strings.Join(cycle, " -> "))
+	}
+	var unsatisfied []string
+	for _, dependency := range k8sInstance.Spec.DependsOn {
+		key := dependencyKey(k8sInstance, dependency)
+		required := dependencyState(dependency)
+		instance := &stratossv1alpha1.Assembly{}
+		if err := sync.k8sClient.Get(context.TODO(), key, instance); err != nil {
+			if errors.IsNotFound(err) {
+				unsatisfied = append(unsatisfied, fmt.Sprintf("%s not found", key))
+				continue
+			}
+			return nil, err
+		}
+		if instance.GetDeletionTimestamp() != nil {
+			unsatisfied = append(unsatisfied, fmt.Sprintf("%s is being deleted", key))
+		} else if instance.Status.State != required {
+			unsatisfied = append(unsatisfied, fmt.Sprintf("%s is %s, waiting for %s", key, instance.Status.State, required))
+		}
+	}
+	return unsatisfied, nil
+}
+
+// dependents returns the Assemblies, in any namespace watched by the operator, which depend on the given Assembly
+func dependents(k8sClient client.Client, key types.NamespacedName) ([]stratossv1alpha1.Assembly, error) {
+	assemblies := &stratossv1alpha1.AssemblyList{}
+	if err := k8sClient.List(context.TODO(), assemblies); err != nil {
+		return nil, err
+	}
+	var found []stratossv1alpha1.Assembly
+	for _, assembly := range assemblies.Items {
+		if dependsOn(&assembly, key) {
+			found = append(found, assembly)
+		}
+	}
+	return found, nil
+}
+
+func (sync *AssemblySynchronizer) setWaitingCondition(reason string, message string) {
+	previous := findCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.WaitingForDependencies)
+	if previous == nil || previous.Status != corev1.ConditionTrue || previous.Reason != reason {
+		sync.recordNormalEvent(EventReasons.WaitingForDependencies, "%s", message)
+	}
+	setCondition(sync.k8sInstance, stratossv1alpha1.ConditionTypes.WaitingForDependencies, corev1.ConditionTrue, reason, message)
+	sync.needsStatusUpdate = true
+}
+
+func (sync *AssemblySynchronizer) clearWaitingCondition() {
+	k8sInstance := sync.k8sInstance
+	previous := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.WaitingForDependencies)
+	if previous == nil && len(k8sInstance.Spec.DependsOn) == 0 {
+		return
+	}
+	if previous != nil && previous.Status == corev1.ConditionFalse && previous.Reason == ConditionReasons.DependenciesSatisfied && previous.ObservedGeneration == k8sInstance.GetGeneration() {
+		return
+	}
+	setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.WaitingForDependencies, corev1.ConditionFalse, ConditionReasons.DependenciesSatisfied, "")
+	sync.needsStatusUpdate = true
+}
+
+// checkDependencies holds back an intent until every Assembly this one depends on is in its required state.
+// The Assembly is reconciled again whenever one of them changes
+func (sync *AssemblySynchronizer) checkDependencies(intentType string) (stopSync bool) {
+	unsatisfied, err := sync.unsatisfiedDependencies()
+	if err != nil {
+		sync.logger.Error(err, "Failed to check Assembly dependencies")
+		return sync.onLMError(err)
+	}
+	if len(unsatisfied) > 0 {
+		sync.logger.Info("Dependencies not satisfied, holding back intent", "intentType", intentType, "dependencies", unsatisfied)
+		sync.setWaitingCondition(ConditionReasons.DependenciesNotSatisfied, fmt.Sprintf("%s held back until dependencies are satisfied: %s", intentType, strings.Join(unsatisfied, "; ")))
+		sync.stopSync = true
+		return sync.stopSync
+	}
+	sync.clearWaitingCondition()
+	return false
+}
+
+// checkDependents holds back deletion of the Assembly from LM until the Assemblies which depend on it have been deleted
+func (sync *AssemblySynchronizer) checkDependents() (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	found, err := dependents(sync.k8sClient, types.NamespacedName{Namespace: k8sInstance.Namespace, Name: k8sInstance.Name})
+	if err != nil {
+		sync.logger.Error(err, "Failed to find dependent Assemblies")
+		return sync.onUpdateError(err)
+	}
+	if len(found) > 0 {
+		var names []string
+		for _, dependent := range found {
+			names = append(names, types.NamespacedName{Namespace: dependent.Namespace, Name: dependent.Name}.String())
+		}
+		sync.logger.Info("Assemblies which depend on this one still exist, holding back deletion", "dependents", names)
+		sync.setWaitingCondition(ConditionReasons.DependentsExist, fmt.Sprintf("Delete held back until dependent Assemblies are deleted: %s", strings.Join(names, ", ")))
+		sync.stopSync = true
+		return sync.stopSync
+	}
+	sync.clearWaitingCondition()
+	return false
+}
+
+// relatedAssemblies maps a change to an Assembly to reconcile requests for the Assemblies which depend on it, which may be waiting for it to
+// reach a state, and the Assemblies it depends on, which may be waiting for it to be deleted
+func relatedAssemblies(k8sClient client.Client) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		key := types.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: obj.Meta.GetName()}
+		var requests []reconcile.Request
+		found, err := dependents(k8sClient, key)
+		if err != nil {
+			log.Error(err, "Failed to list Assemblies depending on changed Assembly", "Namespace", key.Namespace, "Name", key.Name)
+		}
+		for _, dependent := range found {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: dependent.Namespace, Name: dependent.Name}})
+		}
+		if instance, ok := obj.Object.(*stratossv1alpha1.Assembly); ok {
+			for _, dependency := range instance.Spec.DependsOn {
+				requests = append(requests, reconcile.Request{NamespacedName: dependencyKey(instance, dependency)})
+			}
+		}
+		return requests
+	}
+}
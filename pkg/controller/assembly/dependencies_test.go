@@ -0,0 +1,252 @@
+package assembly
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// newDependentAssembly returns an Assembly depending on each of the named Assemblies being Active
+func newDependentAssembly(name string, dependsOn ...string) *stratossv1alpha1.Assembly {
+	spec := activeSpec()
+	for _, dependency := range dependsOn {
+		spec.DependsOn = append(spec.DependsOn, stratossv1alpha1.AssemblyDependency{Name: dependency})
+	}
+	return newTestAssembly(name, spec)
+}
+
+func TestDependencyCycle(t *testing.T) {
+	tests := []struct {
+		name        string
+		assemblies  []*stratossv1alpha1.Assembly
+		expectCycle string
+	}{
+		{
+			name:        "itself",
+			assemblies:  []*stratossv1alpha1.Assembly{newDependentAssembly("a", "a")},
+			expectCycle: "test/a -> test/a",
+		},
+		{
+			name:        "two Assemblies",
+			assemblies:  []*stratossv1alpha1.Assembly{newDependentAssembly("a", "b"), newDependentAssembly("b", "a")},
+			expectCycle: "test/a -> test/b -> test/a",
+		},
+		{
+			name:        "three Assemblies",
+			assemblies:  []*stratossv1alpha1.Assembly{newDependentAssembly("a", "b"), newDependentAssembly("b", "c"), newDependentAssembly("c", "a")},
+			expectCycle: "test/a -> test/b -> test/c -> test/a",
+		},
+		{
+			name:       "chain",
+			assemblies: []*stratossv1alpha1.Assembly{newDependentAssembly("a", "b"), newDependentAssembly("b", "c"), newDependentAssembly("c")},
+		},
+		{
+			name:       "shared dependency",
+			assemblies: []*stratossv1alpha1.Assembly{newDependentAssembly("a", "b", "c"), newDependentAssembly("b", "c"), newDependentAssembly("c")},
+		},
+		{
+			name:       "cycle not leading back",
+			assemblies: []*stratossv1alpha1.Assembly{newDependentAssembly("a", "b"), newDependentAssembly("b", "c"), newDependentAssembly("c", "b")},
+		},
+		{
+			name:       "missing dependency",
+			assemblies: []*stratossv1alpha1.Assembly{newDependentAssembly("a", "b")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			for _, assembly := range tt.assemblies {
+				objs = append(objs, assembly)
+			}
+			env := newTestEnv(t, objs...)
+			defer env.close()
+			sync := newTestSynchronizer(env.get("a"))
+			sync.k8sClient = env.k8sClient
+
+			cycle, err := sync.dependencyCycle()
+			if err != nil {
+				t.Fatalf("Failed to check for a cycle: %s", err)
+			}
+			if actual := strings.Join(cycle, " -> "); actual != tt.expectCycle {
+				t.Errorf("Expected cycle %q but was %q", tt.expectCycle, actual)
+			}
+		})
+	}
+}
+
+func TestReconcileReportsDependencyCycle(t *testing.T) {
+	env := newTestEnv(t, newDependentAssembly("a", "b"), newDependentAssembly("b", "a"))
+	defer env.close()
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := env.reconcile(name); err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("Expected the reconcile of %s to report the cycle but error was %v", name, err)
+		}
+		if status, reason := conditionStatus(env.get(name), stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+			t.Errorf("Expected Synced of %s to be False (%s) but was %s (%s)", name, ConditionReasons.SyncError, status, reason)
+		}
+	}
+	if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 0 {
+		t.Errorf("Expected no Assembly in the cycle to be created but found %d createAssembly requests", len(requests))
+	}
+}
+
+func TestReconcileHoldsBackUntilDependenciesSatisfied(t *testing.T) {
+	env := newTestEnv(t, newDependentAssembly("core"), newDependentAssembly("edge", "core"))
+	defer env.close()
+	env.completeImmediately()
+
+	env.mustReconcile("edge")
+	env.mustReconcile("edge")
+	if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 0 {
+		t.Fatalf("Expected the Create to be held back until the dependency is Active but found %d createAssembly requests", len(requests))
+	}
+	edge := env.get("edge")
+	if status, reason := conditionStatus(edge, stratossv1alpha1.ConditionTypes.WaitingForDependencies); status != corev1.ConditionTrue || reason != ConditionReasons.DependenciesNotSatisfied {
+		t.Errorf("Expected WaitingForDependencies to be True (%s) but was %s (%s)", ConditionReasons.DependenciesNotSatisfied, status, reason)
+	}
+	if status, reason := conditionStatus(edge, stratossv1alpha1.ConditionTypes.Ready); status != corev1.ConditionFalse || reason != ConditionReasons.DependenciesNotSatisfied {
+		t.Errorf("Expected Ready to be False (%s) but was %s (%s)", ConditionReasons.DependenciesNotSatisfied, status, reason)
+	}
+
+	env.reconcileUntil("core", isActive)
+	env.reconcileUntil("edge", isActive)
+	if status, reason := conditionStatus(env.get("edge"), stratossv1alpha1.ConditionTypes.WaitingForDependencies); status != corev1.ConditionFalse {
+		t.Errorf("Expected WaitingForDependencies to be False once the dependency is Active but was %s (%s)", status, reason)
+	}
+	requests := env.lm.IntentRequests("createAssembly")
+	if len(requests) != 2 || !strings.Contains(requests[0].Body, `"assemblyName":"core"`) {
+		t.Errorf("Expected core to be created before edge but requests were %v", requests)
+	}
+}
+
+func TestReconcileDeletesInReverseOrder(t *testing.T) {
+	env := newTestEnv(t, newDependentAssembly("core"), newDependentAssembly("edge", "core"))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("core", isActive)
+	env.reconcileUntil("edge", isActive)
+
+	env.markDeleted("core")
+	env.markDeleted("edge")
+	env.mustReconcile("core")
+	if requests := env.lm.IntentRequests("deleteAssembly"); len(requests) != 0 {
+		t.Fatalf("Expected deletion of core to be held back whilst edge exists but found %d deleteAssembly requests", len(requests))
+	}
+	if status, reason := conditionStatus(env.get("core"), stratossv1alpha1.ConditionTypes.WaitingForDependencies); status != corev1.ConditionTrue || reason != ConditionReasons.DependentsExist {
+		t.Errorf("Expected WaitingForDependencies to be True (%s) but was %s (%s)", ConditionReasons.DependentsExist, status, reason)
+	}
+
+	env.reconcileUntil("edge", func(instance *stratossv1alpha1.Assembly) bool {
+		return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+	})
+	// Removed by Kubernetes once the finalizer is gone, which the fake client does not do itself
+	if err := env.k8sClient.Delete(context.TODO(), env.get("edge")); err != nil {
+		t.Fatalf("Failed to delete edge: %s", err)
+	}
+	env.reconcileUntil("core", func(instance *stratossv1alpha1.Assembly) bool {
+		return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+	})
+	requests := env.lm.IntentRequests("deleteAssembly")
+	if len(requests) != 2 || !strings.Contains(requests[0].Body, `"assemblyName":"edge"`) {
+		t.Errorf("Expected edge to be deleted before core but requests were %v", requests)
+	}
+}
+
+func TestClearWaitingCondition(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependsOn    []string
+		previous     *stratossv1alpha1.Condition
+		expectUpdate bool
+	}{
+		{name: "no dependencies"},
+		{name: "first satisfied", dependsOn: []string{"core"}, expectUpdate: true},
+		{
+			name:         "was waiting",
+			dependsOn:    []string{"core"},
+			previous:     &stratossv1alpha1.Condition{Status: corev1.ConditionTrue, Reason: ConditionReasons.DependenciesNotSatisfied, ObservedGeneration: 1},
+			expectUpdate: true,
+		},
+		{
+			name:      "already satisfied",
+			dependsOn: []string{"core"},
+			previous:  &stratossv1alpha1.Condition{Status: corev1.ConditionFalse, Reason: ConditionReasons.DependenciesSatisfied, ObservedGeneration: 1},
+		},
+		{
+			name:         "dependencies removed",
+			previous:     &stratossv1alpha1.Condition{Status: corev1.ConditionTrue, Reason: ConditionReasons.DependenciesNotSatisfied, ObservedGeneration: 1},
+			expectUpdate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newDependentAssembly("edge", tt.dependsOn...)
+			if tt.previous != nil {
+				condition := *tt.previous
+				condition.Type = stratossv1alpha1.ConditionTypes.WaitingForDependencies
+				instance.Status.Conditions = []stratossv1alpha1.Condition{condition}
+			}
+			sync := newTestSynchronizer(instance)
+
+			sync.clearWaitingCondition()
+			if sync.needsStatusUpdate != tt.expectUpdate {
+				t.Errorf("Expected needsStatusUpdate to be %t but was %t", tt.expectUpdate, sync.needsStatusUpdate)
+			}
+			if condition := findCondition(instance, stratossv1alpha1.ConditionTypes.WaitingForDependencies); tt.expectUpdate && (condition == nil || condition.Status != corev1.ConditionFalse) {
+				t.Errorf("Expected WaitingForDependencies to be False but was %+v", condition)
+			}
+		})
+	}
+}
+
+func TestReconcileRejectsDependencyInAnotherNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		expectErr bool
+	}{
+		{name: "default namespace"},
+		{name: "same namespace", namespace: testNamespace},
+		{name: "another namespace", namespace: "other", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edge := newDependentAssembly("edge")
+			edge.Spec.DependsOn = []stratossv1alpha1.AssemblyDependency{{Name: "core", Namespace: tt.namespace}}
+			env := newTestEnv(t, newDependentAssembly("core"), edge)
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("core", isActive)
+
+			_, err := env.reconcile("edge")
+			if !tt.expectErr {
+				if err != nil {
+					t.Fatalf("Expected the dependency to be accepted: %s", err)
+				}
+				env.reconcileUntil("edge", isActive)
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "spec.dependsOn[0].namespace") {
+				t.Errorf("Expected the reconcile to report spec.dependsOn[0].namespace but error was %v", err)
+			}
+			if status, reason := conditionStatus(env.get("edge"), stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+				t.Errorf("Expected Synced to be False (%s) but was %s (%s)", ConditionReasons.SyncError, status, reason)
+			}
+			if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 1 {
+				t.Errorf("Expected only core to be created but found %d createAssembly requests", len(requests))
+			}
+
+			// Not needed to delete the Assembly
+			env.markDeleted("edge")
+			env.reconcileUntil("edge", func(instance *stratossv1alpha1.Assembly) bool {
+				return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+			})
+		})
+	}
+}
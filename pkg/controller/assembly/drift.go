@@ -0,0 +1,186 @@
+package assembly
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// computePropertyChanges compares the desired properties with those of the Assembly in LM, returning the differences ordered by name.
+// Properties in LM which are not desired are only reported as removed if the operator set them, otherwise they are LM defaults.
+// Values of properties read from Secrets are redacted
+func (sync *AssemblySynchronizer) computePropertyChanges() []stratossv1alpha1.PropertyChange {
+	k8sInstance := sync.k8sInstance
+	ignored := make(map[string]bool)
+	for _, name := range k8sInstance.Spec.IgnoreProperties {
+		ignored[name] = true
+	}
+	observedProperties := sync.lmSourceOfTruth.assemblyProperties()
+	var changes []stratossv1alpha1.PropertyChange
+	for name, desiredValue := range sync.desiredProperties {
+		if ignored[name] {
+			continue
+		}
+		observedValue, found := observedProperties[name]
+		if !found {
+			changes = append(changes, stratossv1alpha1.PropertyChange{
+				Name:         name,
+				Change:       stratossv1alpha1.PropertyChangeTypes.Added,
+				DesiredValue: sync.redactProperty(name, desiredValue),
+			})
+		} else if observedValue != desiredValue {
+			changes = append(changes, stratossv1alpha1.PropertyChange{
+				Name:          name,
+				Change:        stratossv1alpha1.PropertyChangeTypes.Changed,
+				DesiredValue:  sync.redactProperty(name, desiredValue),
+				ObservedValue: sync.redactProperty(name, observedValue),
+			})
+		}
+	}
+	for _, name := range k8sInstance.Status.ManagedProperties {
+		if ignored[name] {
+			continue
+		}
+		if _, desired := sync.desiredProperties[name]; desired {
+			continue
+		}
+		if observedValue, found := observedProperties[name]; found {
+			changes = append(changes, stratossv1alpha1.PropertyChange{
+				Name:          name,
+				Change:        stratossv1alpha1.PropertyChangeTypes.Removed,
+				ObservedValue: sync.redactProperty(name, observedValue),
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// recordManagedProperties adds the names of the properties sent to LM on a Create or Update to those already recorded, so their removal
+// from the spec can be detected. Names from earlier intents are kept until the intent completes, as it may fail to remove them
+func (sync *AssemblySynchronizer) recordManagedProperties() {
+	managed := make(map[string]bool)
+	for _, name := range sync.k8sInstance.Status.ManagedProperties {
+		managed[name] = true
+	}
+	for name := range sync.desiredProperties {
+		managed[name] = true
+	}
+	names := make([]string, 0, len(managed))
+	for name := range managed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sync.k8sInstance.Status.ManagedProperties = names
+}
+
+// pruneManagedProperties narrows the recorded names to the properties desired, once a Create or Update requested for the current desired
+// state has completed and so has removed the others from LM
+func (sync *AssemblySynchronizer) pruneManagedProperties() {
+	k8sInstance := sync.k8sInstance
+	lastProcess := k8sInstance.Status.LastProcess
+	if lastProcess.Status != stratossv1alpha1.ProcessStatus.Completed || (lastProcess.IntentType != "Create" && lastProcess.IntentType != "Update") {
+		return
+	}
+	if lastProcess.DesiredStateHash != sync.desiredStateHash() {
+		// Requested for an earlier desired state, which may have included properties since removed
+		return
+	}
+	pruned := k8sInstance.Status.ManagedProperties[:0:0]
+	for _, name := range k8sInstance.Status.ManagedProperties {
+		if _, desired := sync.desiredProperties[name]; desired {
+			pruned = append(pruned, name)
+		}
+	}
+	if len(pruned) != len(k8sInstance.Status.ManagedProperties) {
+		k8sInstance.Status.ManagedProperties = pruned
+		sync.needsStatusUpdate = true
+	}
+}
+
+// describeDifferences lists how the Assembly in LM differs from the desired state
+func (sync *AssemblySynchronizer) describeDifferences() []string {
+	k8sInstance := sync.k8sInstance
+	if k8sInstance.Status.State == stratossv1alpha1.AssemblyStates.NotFound {
+		return []string{"Assembly no longer exists in LM"}
+	}
+	var differences []string
+	if k8sInstance.Status.State != k8sInstance.Spec.IntendedState {
+		differences = append(differences, fmt.Sprintf("state is %s, desired %s", k8sInstance.Status.State, k8sInstance.Spec.IntendedState))
+	}
+	if k8sInstance.Status.DescriptorName != k8sInstance.Spec.DescriptorName {
+		differences = append(differences, fmt.Sprintf("descriptor is %s, desired %s", k8sInstance.Status.DescriptorName, k8sInstance.Spec.DescriptorName))
+	}
+	for _, change := range sync.computePropertyChanges() {
+		switch change.Change {
+		case stratossv1alpha1.PropertyChangeTypes.Added:
+			differences = append(differences, fmt.Sprintf("property %s is not set, desired %q", change.Name, change.DesiredValue))
+		case stratossv1alpha1.PropertyChangeTypes.Removed:
+			differences = append(differences, fmt.Sprintf("property %s is %q, desired not set", change.Name, change.ObservedValue))
+		default:
+			differences = append(differences, fmt.Sprintf("property %s is %q, desired %q", change.Name, change.ObservedValue, change.DesiredValue))
+		}
+	}
+	return differences
+}
+
+// checkDrift decides what to do about differences between the Assembly in LM and the desired state. Differences found after the desired
+// state has changed are applied as usual. Differences found whilst the desired state is unchanged since LM last matched it were made in LM,
+// outside of the operator, and are handled according to the drift policy
+func (sync *AssemblySynchronizer) checkDrift() (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	if k8sInstance.GetDeletionTimestamp() != nil || sync.adoptionConflict != "" {
+		return false
+	}
+	currentHash := sync.desiredStateHash()
+	differences := sync.describeDifferences()
+	if len(differences) == 0 {
+		if k8sInstance.Status.SyncedSpecHash != currentHash {
+			k8sInstance.Status.SyncedSpecHash = currentHash
+			sync.needsStatusUpdate = true
+		}
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Drifted, corev1.ConditionFalse, ConditionReasons.NoDrift, "")
+		return false
+	}
+	if k8sInstance.Status.SyncedSpecHash == "" || k8sInstance.Status.SyncedSpecHash != currentHash {
+		// Desired state changed, apply it
+		return false
+	}
+
+	message := fmt.Sprintf("Assembly was changed in LM: %s", strings.Join(differences, "; "))
+	previous := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Drifted)
+	alreadyReported := func(reason string) bool {
+		return previous != nil && previous.Status == corev1.ConditionTrue && previous.Reason == reason && previous.Message == message
+	}
+	policy := k8sInstance.Spec.DriftPolicy
+	switch policy {
+	case stratossv1alpha1.DriftPolicies.Report:
+		sync.logger.Info("Assembly was changed in LM, reporting without reverting (driftPolicy Report)", "differences", differences)
+		if !alreadyReported(ConditionReasons.DriftDetected) {
+			sync.recordWarningEvent(EventReasons.Drifted, "%s", message)
+		}
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Drifted, corev1.ConditionTrue, ConditionReasons.DriftDetected, message)
+		sync.needsStatusUpdate = true
+		sync.stopSync = true
+		return sync.stopSync
+	case stratossv1alpha1.DriftPolicies.Ignore:
+		sync.logger.Info("Assembly was changed in LM, leaving change in place (driftPolicy Ignore)", "differences", differences)
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Drifted, corev1.ConditionFalse, ConditionReasons.DriftIgnored, message)
+		sync.needsStatusUpdate = true
+		sync.stopSync = true
+		return sync.stopSync
+	default:
+		sync.logger.Info("Assembly was changed in LM, reverting (driftPolicy Enforce)", "differences", differences)
+		if !alreadyReported(ConditionReasons.DriftReverted) {
+			sync.recordNormalEvent(EventReasons.DriftReverted, "%s, reverting", message)
+		}
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Drifted, corev1.ConditionTrue, ConditionReasons.DriftReverted, message)
+		sync.needsStatusUpdate = true
+		return false
+	}
+}
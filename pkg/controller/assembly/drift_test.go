@@ -0,0 +1,227 @@
+package assembly
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileRemovesPropertyAfterFailedUpdate(t *testing.T) {
+	spec := activeSpec()
+	spec.Properties["zone"] = "a"
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	if managed := env.get("a1").Status.ManagedProperties; !reflect.DeepEqual(managed, []string{"size", "zone"}) {
+		t.Fatalf("Expected the properties sent on Create to be managed but were %v", managed)
+	}
+
+	env.lm.SetProcessLifecycle(fake.UpgradeAssemblyIntent, fake.ProcessLifecycle{FinalStatus: "Failed", StatusReason: "boom"})
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) { delete(instance.Spec.Properties, "zone") })
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.LastProcess.IntentType == "Update" && instance.Status.LastProcess.Status == stratossv1alpha1.ProcessStatus.Failed
+	})
+	instance := env.get("a1")
+	if !reflect.DeepEqual(instance.Status.ManagedProperties, []string{"size", "zone"}) {
+		t.Errorf("Expected the removed property to stay managed whilst the Update has not completed but managed properties were %v", instance.Status.ManagedProperties)
+	}
+	expectedChanges := []stratossv1alpha1.PropertyChange{{Name: "zone", Change: stratossv1alpha1.PropertyChangeTypes.Removed, ObservedValue: "a"}}
+	if !reflect.DeepEqual(instance.Status.PendingChanges, expectedChanges) {
+		t.Errorf("Expected pending changes %+v but were %+v", expectedChanges, instance.Status.PendingChanges)
+	}
+
+	env.completeImmediately()
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+		instance.SetAnnotations(map[string]string{stratossv1alpha1.RetryAnnotation: "1"})
+	})
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		_, found := instance.Status.Properties["zone"]
+		return !found && isActive(instance)
+	})
+	env.mustReconcile("a1")
+	instance = env.get("a1")
+	if !reflect.DeepEqual(instance.Status.ManagedProperties, []string{"size"}) {
+		t.Errorf("Expected the removed property to no longer be managed once the Update completed but managed properties were %v", instance.Status.ManagedProperties)
+	}
+	if len(instance.Status.PendingChanges) != 0 {
+		t.Errorf("Expected no pending changes once the Update completed but were %+v", instance.Status.PendingChanges)
+	}
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 2 {
+		t.Errorf("Expected the failed Update to be requested again but found %d upgradeAssembly requests", len(requests))
+	}
+}
+
+func TestReconcileReportsPendingChanges(t *testing.T) {
+	spec := activeSpec()
+	spec.Properties["zone"] = "a"
+	spec.IgnoreProperties = []string{"zone"}
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+
+	env.lm.SetProcessLifecycle(fake.UpgradeAssemblyIntent, fake.ProcessLifecycle{Steps: []string{"In Progress"}})
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+		instance.Spec.Properties["size"] = "large"
+		instance.Spec.Properties["tier"] = "gold"
+		instance.Spec.Properties["zone"] = "b"
+	})
+	env.mustReconcile("a1")
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 1 {
+		t.Fatalf("Expected an Update to be requested but found %d upgradeAssembly requests", len(requests))
+	}
+	expectedChanges := []stratossv1alpha1.PropertyChange{
+		{Name: "size", Change: stratossv1alpha1.PropertyChangeTypes.Changed, DesiredValue: "large", ObservedValue: "small"},
+		{Name: "tier", Change: stratossv1alpha1.PropertyChangeTypes.Added, DesiredValue: "gold"},
+	}
+	if pending := env.get("a1").Status.PendingChanges; !reflect.DeepEqual(pending, expectedChanges) {
+		t.Errorf("Expected pending changes %+v, without the ignored property, but were %+v", expectedChanges, pending)
+	}
+
+	// Neither changing nor removing an ignored property is a difference
+	env.completeImmediately()
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.Properties["tier"] == "gold" && isActive(instance)
+	})
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) { delete(instance.Spec.Properties, "zone") })
+	env.mustReconcile("a1")
+	env.mustReconcile("a1")
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 1 {
+		t.Errorf("Expected no Update for the removal of an ignored property but found %d upgradeAssembly requests", len(requests))
+	}
+	if pending := env.get("a1").Status.PendingChanges; len(pending) != 0 {
+		t.Errorf("Expected no pending changes for an ignored property but were %+v", pending)
+	}
+}
+
+// isInSync is true once the Assembly is Active and LM has been seen to match the desired state, after which differences are drift
+func isInSync(instance *stratossv1alpha1.Assembly) bool {
+	return instance.Status.SyncedSpecHash != "" && isActive(instance)
+}
+
+// changeInLM changes the Assembly in LM outside of the operator
+func (env *testEnv) changeInLM(name string, state string, size string) {
+	env.t.Helper()
+	assembly, found := env.lm.GetAssembly(name)
+	if !found {
+		env.t.Fatalf("Assembly %s not found in LM", name)
+	}
+	assembly.State = state
+	assembly.Properties = []lm.AssemblyProperty{{Name: "size", Value: size}}
+	env.lm.AddAssembly(assembly)
+}
+
+// resync reconciles the Assembly once its resync is due, so changes made in LM are seen
+func (env *testEnv) resync(name string) {
+	env.t.Helper()
+	instance := env.get(name)
+	lastSyncTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	instance.Status.LastSyncTime = &lastSyncTime
+	if err := env.k8sClient.Status().Update(context.TODO(), instance); err != nil {
+		env.t.Fatalf("Failed to update status: %s", err)
+	}
+	env.mustReconcile(name)
+}
+
+func TestReconcileDriftPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		state        string
+		size         string
+		expectIntent string
+	}{
+		{name: "Report", policy: stratossv1alpha1.DriftPolicies.Report, state: stratossv1alpha1.AssemblyStates.Active, size: "large"},
+		{name: "Ignore", policy: stratossv1alpha1.DriftPolicies.Ignore, state: stratossv1alpha1.AssemblyStates.Active, size: "large"},
+		{name: "Enforce property", policy: stratossv1alpha1.DriftPolicies.Enforce, state: stratossv1alpha1.AssemblyStates.Active, size: "large", expectIntent: "upgradeAssembly"},
+		{name: "Enforce state", policy: stratossv1alpha1.DriftPolicies.Enforce, state: stratossv1alpha1.AssemblyStates.Inactive, size: "small", expectIntent: "changeAssemblyState"},
+		{name: "default", state: stratossv1alpha1.AssemblyStates.Active, size: "large", expectIntent: "upgradeAssembly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := activeSpec()
+			spec.DriftPolicy = tt.policy
+			env := newTestEnv(t, newTestAssembly("a1", spec))
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", isInSync)
+			requests := env.intentRequests()
+			env.events()
+
+			env.changeInLM("a1", tt.state, tt.size)
+			env.resync("a1")
+			env.resync("a1")
+			instance := env.get("a1")
+			events := env.events()
+			if tt.expectIntent != "" {
+				if requests := env.lm.IntentRequests(tt.expectIntent); len(requests) != 1 {
+					t.Errorf("Expected the change in LM to be reverted with a single %s request but found %d", tt.expectIntent, len(requests))
+				}
+				if count := countEvents(events, EventReasons.DriftReverted); count != 1 {
+					t.Errorf("Expected a single %s event but found %v", EventReasons.DriftReverted, events)
+				}
+				env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+					return instance.Status.Properties["size"] == "small" && isActive(instance)
+				})
+				if status, reason := conditionStatus(env.get("a1"), stratossv1alpha1.ConditionTypes.Drifted); status != corev1.ConditionFalse || reason != ConditionReasons.NoDrift {
+					t.Errorf("Expected Drifted to be False (%s) once reverted but was %s (%s)", ConditionReasons.NoDrift, status, reason)
+				}
+				return
+			}
+			if actual := env.intentRequests(); actual != requests {
+				t.Errorf("Expected the change in LM to be left in place but found %d intent requests", actual-requests)
+			}
+			if instance.Status.Properties["size"] != tt.size {
+				t.Errorf("Expected the status to show the change in LM but properties were %v", instance.Status.Properties)
+			}
+			status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Drifted)
+			switch tt.policy {
+			case stratossv1alpha1.DriftPolicies.Report:
+				if status != corev1.ConditionTrue || reason != ConditionReasons.DriftDetected {
+					t.Errorf("Expected Drifted to be True (%s) but was %s (%s)", ConditionReasons.DriftDetected, status, reason)
+				}
+				if count := countEvents(events, EventReasons.Drifted); count != 1 {
+					t.Errorf("Expected a single %s event but found %v", EventReasons.Drifted, events)
+				}
+			case stratossv1alpha1.DriftPolicies.Ignore:
+				if status != corev1.ConditionFalse || reason != ConditionReasons.DriftIgnored {
+					t.Errorf("Expected Drifted to be False (%s) but was %s (%s)", ConditionReasons.DriftIgnored, status, reason)
+				}
+				if len(events) != 0 {
+					t.Errorf("Expected no events but found %v", events)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileAppliesSpecChangeWithReportDriftPolicy(t *testing.T) {
+	spec := activeSpec()
+	spec.DriftPolicy = stratossv1alpha1.DriftPolicies.Report
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isInSync)
+	env.changeInLM("a1", stratossv1alpha1.AssemblyStates.Active, "large")
+	env.resync("a1")
+	if status, reason := conditionStatus(env.get("a1"), stratossv1alpha1.ConditionTypes.Drifted); status != corev1.ConditionTrue {
+		t.Fatalf("Expected Drifted to be True but was %s (%s)", status, reason)
+	}
+
+	env.edit("a1", setSize("medium"))
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		status, _ := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Drifted)
+		return instance.Status.Properties["size"] == "medium" && isActive(instance) && status == corev1.ConditionFalse
+	})
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 1 {
+		t.Errorf("Expected the spec change to be applied with a single upgradeAssembly request but found %d", len(requests))
+	}
+}
@@ -0,0 +1,66 @@
+package assembly
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+type eventReasons struct {
+	IntentRequested        string
+	IntentPlanned          string
+	ProcessCompleted       string
+	ProcessFailed          string
+	ProcessCancelled       string
+	SyncError              string
+	FinalizerRemoved       string
+	Orphaned               string
+	Adopted                string
+	AdoptionConflict       string
+	Suspended              string
+	Resumed                string
+	RetriesExhausted       string
+	Drifted                string
+	DriftReverted          string
+	WaitingForDependencies string
+	ApprovalRequired       string
+	Approved               string
+	IntentDeferred         string
+	PlanOutdated           string
+}
+
+var EventReasons = &eventReasons{
+	IntentRequested:        "IntentRequested",
+	IntentPlanned:          "IntentPlanned",
+	ProcessCompleted:       "ProcessCompleted",
+	ProcessFailed:          "ProcessFailed",
+	ProcessCancelled:       "ProcessCancelled",
+	SyncError:              "SyncError",
+	FinalizerRemoved:       "FinalizerRemoved",
+	Orphaned:               "Orphaned",
+	Adopted:                "Adopted",
+	AdoptionConflict:       "AdoptionConflict",
+	Suspended:              "Suspended",
+	Resumed:                "Resumed",
+	RetriesExhausted:       "RetriesExhausted",
+	Drifted:                "Drifted",
+	DriftReverted:          "DriftReverted",
+	WaitingForDependencies: "WaitingForDependencies",
+	ApprovalRequired:       "ApprovalRequired",
+	Approved:               "Approved",
+	IntentDeferred:         "IntentDeferred",
+	PlanOutdated:           "PlanOutdated",
+}
+
+func (sync *AssemblySynchronizer) recordNormalEvent(reason string, messageFmt string, args ...interface{}) {
+	sync.recordEvent(corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+func (sync *AssemblySynchronizer) recordWarningEvent(reason string, messageFmt string, args ...interface{}) {
+	sync.recordEvent(corev1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+func (sync *AssemblySynchronizer) recordEvent(eventType string, reason string, messageFmt string, args ...interface{}) {
+	if sync.recorder == nil {
+		return
+	}
+	sync.recorder.Eventf(sync.k8sInstance, eventType, reason, messageFmt, args...)
+}
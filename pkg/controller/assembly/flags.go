@@ -0,0 +1,61 @@
+package assembly
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// options holds the settings of the Assembly controller configured on the command line
+type options struct {
+	namingStrategy          string
+	nameTemplate            string
+	polling                 pollSettings
+	maxConcurrentReconciles int
+	rateLimit               rateLimitSettings
+}
+
+var controllerOptions = options{
+	namingStrategy: NamingStrategies.Plain,
+	polling: pollSettings{
+		initialInterval: 2 * time.Second,
+		maxInterval:     time.Minute,
+		jitter:          0.2,
+		resyncPeriod:    10 * time.Minute,
+	},
+	maxConcurrentReconciles: 1,
+	rateLimit: rateLimitSettings{
+		baseDelay: 5 * time.Millisecond,
+		maxDelay:  1000 * time.Second,
+		qps:       10,
+		burst:     100,
+	},
+}
+
+// FlagSet returns the command line flags of the Assembly controller. The flag set must be added before calling pflag.Parse()
+func FlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("assembly", pflag.ExitOnError)
+	flagSet.StringVar(&controllerOptions.namingStrategy, "assembly-naming-strategy", controllerOptions.namingStrategy,
+		"How the name of an Assembly in LM is derived from the Kubernetes resource, when spec.assemblyName is not set: plain (the resource name), namespace-prefixed (<namespace>.<name>) or template (see --assembly-name-template)")
+	flagSet.StringVar(&controllerOptions.nameTemplate, "assembly-name-template", controllerOptions.nameTemplate,
+		"Go template producing the name of an Assembly in LM, used with the template naming strategy (e.g. \"cluster1.{{ .Namespace }}.{{ .Name }}\")")
+	flagSet.DurationVar(&controllerOptions.polling.initialInterval, "process-poll-initial-interval", controllerOptions.polling.initialInterval,
+		"Delay before the first check on the progress of a new LM process, doubled after each check")
+	flagSet.DurationVar(&controllerOptions.polling.maxInterval, "process-poll-max-interval", controllerOptions.polling.maxInterval,
+		"Upper limit on the delay between checks on the progress of an LM process")
+	flagSet.Float64Var(&controllerOptions.polling.jitter, "process-poll-jitter", controllerOptions.polling.jitter,
+		"Randomization factor (0 to 1) applied to each polling delay, so Assemblies are not checked in step")
+	flagSet.DurationVar(&controllerOptions.polling.resyncPeriod, "resync-period", controllerOptions.polling.resyncPeriod,
+		"Period between checks on an Assembly for changes made in LM outside of the operator, when no process is in progress (0 disables them)")
+	flagSet.IntVar(&controllerOptions.maxConcurrentReconciles, "max-concurrent-reconciles", controllerOptions.maxConcurrentReconciles,
+		"Maximum number of Assemblies reconciled at the same time. The same Assembly is never reconciled more than once at a time")
+	flagSet.DurationVar(&controllerOptions.rateLimit.baseDelay, "rate-limit-base-delay", controllerOptions.rateLimit.baseDelay,
+		"Delay before an Assembly is reconciled again after a failed reconcile, doubled on each consecutive failure")
+	flagSet.DurationVar(&controllerOptions.rateLimit.maxDelay, "rate-limit-max-delay", controllerOptions.rateLimit.maxDelay,
+		"Upper limit on the delay before an Assembly is reconciled again after a failed reconcile")
+	flagSet.Float64Var(&controllerOptions.rateLimit.qps, "rate-limit-qps", controllerOptions.rateLimit.qps,
+		"Overall rate, across all Assemblies, at which failed reconciles are run again")
+	flagSet.IntVar(&controllerOptions.rateLimit.burst, "rate-limit-burst", controllerOptions.rateLimit.burst,
+		"Number of failed reconciles which may be run again at once above --rate-limit-qps")
+	return flagSet
+}
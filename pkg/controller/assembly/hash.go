@@ -0,0 +1,87 @@
+package assembly
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+)
+
+// specHash returns a short hash identifying the content of an Assembly spec, used to detect when it has changed
+func specHash(spec stratossv1alpha1.AssemblySpec) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		// Not expected for a type generated from a CRD, treat every spec as new
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// desiredState is the part of an Assembly spec which defines the Assembly in LM
+type desiredState struct {
+	AssemblyName     string                            `json:"assemblyName"`
+	DescriptorName   string                            `json:"descriptorName"`
+	IntendedState    string                            `json:"intendedState"`
+	Properties       map[string]string                 `json:"properties"`
+	PropertiesFrom   []stratossv1alpha1.PropertySource `json:"propertiesFrom"`
+	IgnoreProperties []string                          `json:"ignoreProperties"`
+	SourceVersions   []string                          `json:"sourceVersions"`
+}
+
+// desiredStateHash returns a short hash identifying the desired state of the Assembly in LM. Referenced Secrets and ConfigMaps are
+// identified by their resource versions, so a change to them changes the hash without their values being part of it
+func (sync *AssemblySynchronizer) desiredStateHash() string {
+	spec := sync.k8sInstance.Spec
+	sourceVersions := append([]string(nil), sync.sourceVersions...)
+	sort.Strings(sourceVersions)
+	data, err := json.Marshal(desiredState{
+		AssemblyName:     spec.AssemblyName,
+		DescriptorName:   spec.DescriptorName,
+		IntendedState:    spec.IntendedState,
+		Properties:       spec.Properties,
+		PropertiesFrom:   spec.PropertiesFrom,
+		IgnoreProperties: spec.IgnoreProperties,
+		SourceVersions:   sourceVersions,
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// reconcileInputs is everything, other than the state of the Assembly in LM, which decides what the operator does with an Assembly
+type reconcileInputs struct {
+	Spec           stratossv1alpha1.AssemblySpec `json:"spec"`
+	Annotations    map[string]string             `json:"annotations"`
+	SourceVersions []string                      `json:"sourceVersions"`
+	OutputVersions []string                      `json:"outputVersions"`
+}
+
+// reconcileInputsHash returns a short hash identifying the spec, operator annotations, versions of referenced Secrets and ConfigMaps
+// and versions of the outputs published by the operator, so an output changed by anything else is published again
+func (sync *AssemblySynchronizer) reconcileInputsHash() string {
+	annotations := make(map[string]string)
+	for key, value := range sync.k8sInstance.GetAnnotations() {
+		if strings.HasPrefix(key, operatorAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+	sourceVersions := append([]string(nil), sync.sourceVersions...)
+	sort.Strings(sourceVersions)
+	data, err := json.Marshal(reconcileInputs{
+		Spec:           sync.k8sInstance.Spec,
+		Annotations:    annotations,
+		SourceVersions: sourceVersions,
+		OutputVersions: sync.outputVersions,
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,198 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/accanto/assembly-operator/internal/cron"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Key of the ConfigMap referenced by spec.maintenance.windowsFrom which lists the shared windows
+const maintenanceWindowsKey = "windows"
+
+// sharedMaintenanceWindow is a window listed in a ConfigMap, in the same form as spec.maintenance.windows
+type sharedMaintenanceWindow struct {
+	Schedule string `yaml:"schedule"`
+	Duration string `yaml:"duration"`
+	TimeZone string `yaml:"timeZone"`
+}
+
+// referencesMaintenanceWindows returns true if the Assembly reads maintenance windows from the named ConfigMap
+func referencesMaintenanceWindows(instance *stratossv1alpha1.Assembly, name string) bool {
+	policy := instance.Spec.Maintenance
+	return policy != nil && policy.WindowsFrom != nil && policy.WindowsFrom.Name == name
+}
+
+// deferredToWindow returns true if an intent of the given type is only requested within a maintenance window
+func deferredToWindow(policy *stratossv1alpha1.MaintenancePolicy, intentType string) bool {
+	switch intentType {
+	case "Create":
+		return policy.IncludeCreate
+	case "Delete":
+		return policy.IncludeDelete
+	}
+	return true
+}
+
+// readSharedMaintenanceWindows reads the windows listed in the ConfigMap referenced by spec.maintenance.windowsFrom
+func (sync *AssemblySynchronizer) readSharedMaintenanceWindows(name string) ([]stratossv1alpha1.MaintenanceWindow, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := sync.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: sync.k8sInstance.Namespace, Name: name}, configMap); err != nil {
+		return nil, fmt.Errorf("Unable to read maintenance windows from ConfigMap %s: %s", name, err)
+	}
+	data, ok := configMap.Data[maintenanceWindowsKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s has no %s key listing maintenance windows", name, maintenanceWindowsKey)
+	}
+	var shared []sharedMaintenanceWindow
+	if err := yaml.Unmarshal([]byte(data), &shared); err != nil {
+		return nil, fmt.Errorf("Unable to parse maintenance windows in ConfigMap %s: %s", name, err)
+	}
+	windows := make([]stratossv1alpha1.MaintenanceWindow, 0, len(shared))
+	for _, window := range shared {
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid duration of maintenance window %q in ConfigMap %s: %s", window.Schedule, name, err)
+		}
+		windows = append(windows, stratossv1alpha1.MaintenanceWindow{Schedule: window.Schedule, Duration: metav1.Duration{Duration: duration}, TimeZone: window.TimeZone})
+	}
+	return windows, nil
+}
+
+// windowAt returns the occurrence of a window which is open at the given time, or else the next to open
+func windowAt(window stratossv1alpha1.MaintenanceWindow, now time.Time) (stratossv1alpha1.ScheduledWindow, error) {
+	location := time.UTC
+	if window.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(window.TimeZone); err != nil {
+			return stratossv1alpha1.ScheduledWindow{}, fmt.Errorf("Invalid time zone %q of maintenance window %q: %s", window.TimeZone, window.Schedule, err)
+		}
+	}
+	if window.Duration.Duration <= 0 {
+		return stratossv1alpha1.ScheduledWindow{}, fmt.Errorf("Maintenance window %q must have a positive duration", window.Schedule)
+	}
+	schedule, err := cron.Parse(window.Schedule)
+	if err != nil {
+		return stratossv1alpha1.ScheduledWindow{}, err
+	}
+	// The first opening after the time a window open now would have opened at is either that window, or the next
+	start := schedule.Next(now.In(location).Add(-window.Duration.Duration))
+	if start.IsZero() {
+		return stratossv1alpha1.ScheduledWindow{}, fmt.Errorf("Maintenance window %q never opens", window.Schedule)
+	}
+	return stratossv1alpha1.ScheduledWindow{
+		Start: metav1.NewTime(start),
+		End:   metav1.NewTime(start.Add(window.Duration.Duration)),
+	}, nil
+}
+
+// nextMaintenanceWindow returns the window which is open at the given time, closing last if several are, or else the next to open
+func nextMaintenanceWindow(windows []stratossv1alpha1.MaintenanceWindow, now time.Time) (*stratossv1alpha1.ScheduledWindow, error) {
+	var next *stratossv1alpha1.ScheduledWindow
+	for _, window := range windows {
+		scheduled, err := windowAt(window, now)
+		if err != nil {
+			return nil, err
+		}
+		open := !scheduled.Start.Time.After(now)
+		switch {
+		case next == nil:
+			next = &scheduled
+		case open && next.Start.Time.After(now):
+			next = &scheduled
+		case open && scheduled.End.Time.After(next.End.Time):
+			next = &scheduled
+		case !open && next.Start.Time.After(now) && scheduled.Start.Time.Before(next.Start.Time):
+			next = &scheduled
+		}
+	}
+	return next, nil
+}
+
+// untilMaintenanceWindowChanges returns how long until the maintenance window recorded on the status closes, if it is open, or else opens,
+// when the status is out of date and must be resolved again. Not found when no window is recorded
+func untilMaintenanceWindowChanges(instance *stratossv1alpha1.Assembly, now time.Time) (time.Duration, bool) {
+	window := instance.Status.NextMaintenanceWindow
+	if instance.Spec.Maintenance == nil || window == nil {
+		return 0, false
+	}
+	if window.Start.Time.After(now) {
+		return window.Start.Time.Sub(now), true
+	}
+	return window.End.Time.Sub(now), true
+}
+
+// resolveMaintenanceWindow records the maintenance window which is open, or else the next to open, on the status. Windows which cannot be
+// resolved are left to checkMaintenanceWindow to report, so they do not hold back intents which are not deferred to them
+func (sync *AssemblySynchronizer) resolveMaintenanceWindow() {
+	k8sInstance := sync.k8sInstance
+	policy := k8sInstance.Spec.Maintenance
+	if policy == nil {
+		if k8sInstance.Status.NextMaintenanceWindow != nil {
+			k8sInstance.Status.NextMaintenanceWindow = nil
+			sync.needsStatusUpdate = true
+		}
+		return
+	}
+	next, err := sync.nextMaintenanceWindow(policy)
+	if err != nil {
+		sync.logger.Error(err, "Failed to resolve maintenance windows")
+		sync.maintenanceWindowErr = err
+	}
+	k8sInstance.Status.NextMaintenanceWindow = next
+	sync.needsStatusUpdate = true
+}
+
+// nextMaintenanceWindow returns the window of the maintenance policy, including those shared in a ConfigMap, which is open or else the next to open
+func (sync *AssemblySynchronizer) nextMaintenanceWindow(policy *stratossv1alpha1.MaintenancePolicy) (*stratossv1alpha1.ScheduledWindow, error) {
+	windows := policy.Windows
+	if policy.WindowsFrom != nil {
+		shared, err := sync.readSharedMaintenanceWindows(policy.WindowsFrom.Name)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(append([]stratossv1alpha1.MaintenanceWindow{}, windows...), shared...)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("Maintenance policy has no windows, so intents would never be requested")
+	}
+	return nextMaintenanceWindow(windows, time.Now())
+}
+
+// checkMaintenanceWindow defers an intent which disrupts the Assembly until the next maintenance window opens, requeueing the reconcile for that time
+func (sync *AssemblySynchronizer) checkMaintenanceWindow(intentType string) (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	policy := k8sInstance.Spec.Maintenance
+	window := k8sInstance.Status.NextMaintenanceWindow
+	if policy == nil || !deferredToWindow(policy, intentType) {
+		return false
+	}
+	if sync.maintenanceWindowErr != nil {
+		return sync.onLMError(sync.maintenanceWindowErr)
+	}
+	if window == nil {
+		return false
+	}
+	now := time.Now()
+	if !window.Start.Time.After(now) {
+		sync.logger.Info("Within maintenance window", "intentType", intentType, "windowEnd", window.End.Time)
+		return false
+	}
+	message := fmt.Sprintf("%s deferred until the maintenance window opening at %s", intentType, window.Start.Time.Format(time.RFC3339))
+	sync.logger.Info("Outside of maintenance windows, deferring intent", "intentType", intentType, "windowStart", window.Start.Time)
+	if ready := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready); ready == nil || ready.Reason != ConditionReasons.MaintenanceWindowClosed || ready.Message != message {
+		sync.recordNormalEvent(EventReasons.IntentDeferred, "%s", message)
+	}
+	sync.intentDeferred = message
+	sync.needsStatusUpdate = true
+	sync.requeue = true
+	sync.requeueDelay = window.Start.Time.Sub(now)
+	sync.stopSync = true
+	return sync.stopSync
+}
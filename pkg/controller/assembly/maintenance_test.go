@@ -0,0 +1,242 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func maintenanceWindow(schedule string, duration time.Duration, timeZone string) stratossv1alpha1.MaintenanceWindow {
+	return stratossv1alpha1.MaintenanceWindow{Schedule: schedule, Duration: metav1.Duration{Duration: duration}, TimeZone: timeZone}
+}
+
+func TestWindowAt(t *testing.T) {
+	nightly := maintenanceWindow("0 2 * * *", 2*time.Hour, "")
+	tests := []struct {
+		name        string
+		window      stratossv1alpha1.MaintenanceWindow
+		now         time.Time
+		expectStart time.Time
+		expectError bool
+	}{
+		{name: "before opening", window: nightly, now: time.Date(2026, 6, 1, 1, 0, 0, 0, time.UTC), expectStart: time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC)},
+		{name: "opening", window: nightly, now: time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC), expectStart: time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC)},
+		{name: "open", window: nightly, now: time.Date(2026, 6, 1, 3, 59, 0, 0, time.UTC), expectStart: time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC)},
+		{name: "closing", window: nightly, now: time.Date(2026, 6, 1, 4, 0, 0, 0, time.UTC), expectStart: time.Date(2026, 6, 2, 2, 0, 0, 0, time.UTC)},
+		{name: "open longer than between openings", window: maintenanceWindow("0 * * * *", 90*time.Minute, ""), now: time.Date(2026, 6, 1, 2, 10, 0, 0, time.UTC), expectStart: time.Date(2026, 6, 1, 1, 0, 0, 0, time.UTC)},
+		{name: "time zone", window: maintenanceWindow("0 2 * * *", 2*time.Hour, "Europe/London"), now: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), expectStart: time.Date(2026, 6, 1, 1, 0, 0, 0, time.UTC)},
+		// Clocks go forward from 01:00 to 02:00 on 29 March, so the window does not open that day
+		{
+			name:        "time zone when clocks go forward",
+			window:      maintenanceWindow("30 1 * * *", 2*time.Hour, "Europe/London"),
+			now:         time.Date(2026, 3, 29, 1, 10, 0, 0, time.UTC),
+			expectStart: time.Date(2026, 3, 30, 0, 30, 0, 0, time.UTC),
+		},
+		// Clocks go back from 02:00 to 01:00 on 25 October, so the window opens at 02:30 GMT
+		{
+			name:        "time zone when clocks go back",
+			window:      maintenanceWindow("30 2 * * *", time.Hour, "Europe/London"),
+			now:         time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC),
+			expectStart: time.Date(2026, 10, 25, 2, 30, 0, 0, time.UTC),
+		},
+		{name: "invalid time zone", window: maintenanceWindow("0 2 * * *", time.Hour, "Nowhere/Special"), now: time.Now(), expectError: true},
+		{name: "no duration", window: maintenanceWindow("0 2 * * *", 0, ""), now: time.Now(), expectError: true},
+		{name: "invalid schedule", window: maintenanceWindow("0 2 * *", time.Hour, ""), now: time.Now(), expectError: true},
+		{name: "never opens", window: maintenanceWindow("0 2 30 2 *", time.Hour, ""), now: time.Now(), expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.window.TimeZone != "" && !tt.expectError {
+				if _, err := time.LoadLocation(tt.window.TimeZone); err != nil {
+					t.Skipf("Time zone %s is not available: %s", tt.window.TimeZone, err)
+				}
+			}
+			scheduled, err := windowAt(tt.window, tt.now)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected window %+v to be rejected but was %+v", tt.window, scheduled)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to resolve window: %s", err)
+			}
+			if !scheduled.Start.Time.Equal(tt.expectStart) || !scheduled.End.Time.Equal(tt.expectStart.Add(tt.window.Duration.Duration)) {
+				t.Errorf("Expected the window to open at %s for %s but was %s to %s", tt.expectStart, tt.window.Duration.Duration, scheduled.Start.Time, scheduled.End.Time)
+			}
+		})
+	}
+}
+
+func TestNextMaintenanceWindow(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		windows     []stratossv1alpha1.MaintenanceWindow
+		expectStart time.Time
+		expectEnd   time.Time
+	}{
+		{
+			name:        "next to open",
+			windows:     []stratossv1alpha1.MaintenanceWindow{maintenanceWindow("0 18 * * *", time.Hour, ""), maintenanceWindow("0 14 * * *", time.Hour, "")},
+			expectStart: time.Date(2026, 6, 1, 14, 0, 0, 0, time.UTC),
+			expectEnd:   time.Date(2026, 6, 1, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "open before next to open",
+			windows:     []stratossv1alpha1.MaintenanceWindow{maintenanceWindow("0 13 * * *", time.Hour, ""), maintenanceWindow("0 11 * * *", 2*time.Hour, "")},
+			expectStart: time.Date(2026, 6, 1, 11, 0, 0, 0, time.UTC),
+			expectEnd:   time.Date(2026, 6, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "open closing last",
+			windows:     []stratossv1alpha1.MaintenanceWindow{maintenanceWindow("0 10 * * *", 3*time.Hour, ""), maintenanceWindow("0 11 * * *", 4*time.Hour, ""), maintenanceWindow("30 11 * * *", time.Hour, "")},
+			expectStart: time.Date(2026, 6, 1, 11, 0, 0, 0, time.UTC),
+			expectEnd:   time.Date(2026, 6, 1, 15, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := nextMaintenanceWindow(tt.windows, now)
+			if err != nil {
+				t.Fatalf("Failed to resolve windows: %s", err)
+			}
+			if next == nil || !next.Start.Time.Equal(tt.expectStart) || !next.End.Time.Equal(tt.expectEnd) {
+				t.Errorf("Expected the window from %s to %s but was %+v", tt.expectStart, tt.expectEnd, next)
+			}
+		})
+	}
+	if _, err := nextMaintenanceWindow([]stratossv1alpha1.MaintenanceWindow{maintenanceWindow("0 2 * * *", time.Hour, ""), maintenanceWindow("0 2 * *", time.Hour, "")}, now); err == nil {
+		t.Errorf("Expected an invalid window to be reported")
+	}
+}
+
+// soonMaintenanceSpec returns a spec with a maintenance window opening within the next few minutes, well before the resync is due
+func soonMaintenanceSpec() stratossv1alpha1.AssemblySpec {
+	spec := activeSpec()
+	minute := (time.Now().Minute() + 3) % 60
+	spec.Maintenance = &stratossv1alpha1.MaintenancePolicy{
+		Windows: []stratossv1alpha1.MaintenanceWindow{maintenanceWindow(fmt.Sprintf("%d * * * *", minute), time.Minute, "")},
+	}
+	return spec
+}
+
+func TestReconcileRequeuedWhenMaintenanceWindowChanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		resyncPeriod *metav1.Duration
+	}{
+		{name: "resync enabled"},
+		{name: "resync disabled", resyncPeriod: &metav1.Duration{Duration: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := soonMaintenanceSpec()
+			if tt.resyncPeriod != nil {
+				spec.Polling = &stratossv1alpha1.PollingPolicy{ResyncPeriod: tt.resyncPeriod}
+			}
+			env := newTestEnv(t, newTestAssembly("a1", spec))
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				return instance.Status.LastSyncHash != ""
+			})
+			window := env.get("a1").Status.NextMaintenanceWindow
+			if window == nil {
+				t.Fatalf("Expected the next maintenance window to be recorded")
+			}
+
+			// Skipped, as nothing has changed, but requeued for when the window opens
+			requests := len(env.lm.Requests())
+			untilOpen := time.Until(window.Start.Time)
+			result := env.mustReconcile("a1")
+			if len(env.lm.Requests()) != requests {
+				t.Errorf("Expected the reconcile to be skipped")
+			}
+			if result.RequeueAfter <= 0 || result.RequeueAfter > untilOpen {
+				t.Errorf("Expected the skipped reconcile to be requeued by the time the window opens at %s but was %+v", window.Start.Time, result)
+			}
+
+			// Once the window recorded has closed the status is out of date, so the reconcile is not skipped
+			instance := env.get("a1")
+			instance.Status.NextMaintenanceWindow = &stratossv1alpha1.ScheduledWindow{
+				Start: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+				End:   metav1.NewTime(time.Now().Add(-time.Hour)),
+			}
+			if err := env.k8sClient.Status().Update(context.TODO(), instance); err != nil {
+				t.Fatalf("Failed to update status: %s", err)
+			}
+			before := time.Now()
+			result = env.mustReconcile("a1")
+			if len(env.lm.Requests()) == requests {
+				t.Errorf("Expected the reconcile not to be skipped once the maintenance window recorded has closed")
+			}
+			refreshed := env.get("a1").Status.NextMaintenanceWindow
+			if refreshed == nil || !refreshed.End.Time.After(time.Now()) {
+				t.Errorf("Expected the maintenance window to be resolved again but was %+v", refreshed)
+			}
+			if result.RequeueAfter <= 0 || result.RequeueAfter > refreshed.End.Time.Sub(before) {
+				t.Errorf("Expected the reconcile to be requeued by the time the window changes but was %+v", result)
+			}
+		})
+	}
+}
+
+// missingWindowsSpec returns a spec with maintenance windows shared in a ConfigMap which does not exist
+func missingWindowsSpec(includeCreate bool) stratossv1alpha1.AssemblySpec {
+	spec := activeSpec()
+	spec.Maintenance = &stratossv1alpha1.MaintenancePolicy{
+		WindowsFrom:   &corev1.LocalObjectReference{Name: "missing-windows"},
+		IncludeCreate: includeCreate,
+	}
+	return spec
+}
+
+func TestReconcileOnlyReportsUnresolvedWindowsForDeferredIntents(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", missingWindowsSpec(false)))
+	defer env.close()
+	env.completeImmediately()
+
+	// Create and Delete are not deferred to the windows, so are requested
+	env.reconcileUntil("a1", isActive)
+	if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 1 {
+		t.Fatalf("Expected a single createAssembly request but found %d", len(requests))
+	}
+
+	env.edit("a1", setSize("large"))
+	if _, err := env.reconcile("a1"); err == nil || !strings.Contains(err.Error(), "missing-windows") {
+		t.Errorf("Expected the reconcile to report the missing ConfigMap but error was %v", err)
+	}
+	if status, reason := conditionStatus(env.get("a1"), stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+		t.Errorf("Expected Synced to be False (%s) but was %s (%s)", ConditionReasons.SyncError, status, reason)
+	}
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 0 {
+		t.Errorf("Expected the Update to be held back but found %d upgradeAssembly requests", len(requests))
+	}
+
+	env.markDeleted("a1")
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+	})
+	if requests := env.lm.IntentRequests("deleteAssembly"); len(requests) != 1 {
+		t.Errorf("Expected a single deleteAssembly request but found %d", len(requests))
+	}
+}
+
+func TestReconcileReportsUnresolvedWindowsForDeferredCreate(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", missingWindowsSpec(true)))
+	defer env.close()
+
+	if _, err := env.reconcile("a1"); err == nil || !strings.Contains(err.Error(), "missing-windows") {
+		t.Errorf("Expected the reconcile to report the missing ConfigMap but error was %v", err)
+	}
+	if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 0 {
+		t.Errorf("Expected the Create to be held back but found %d createAssembly requests", len(requests))
+	}
+}
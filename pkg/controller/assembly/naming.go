@@ -0,0 +1,109 @@
+package assembly
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+)
+
+type namingStrategies struct {
+	Plain             string
+	NamespacePrefixed string
+	Template          string
+}
+
+var NamingStrategies = &namingStrategies{
+	Plain:             "plain",
+	NamespacePrefixed: "namespace-prefixed",
+	Template:          "template",
+}
+
+// assemblyNamer derives the name of an Assembly in LM from the Kubernetes resource, when spec.assemblyName is not set
+type assemblyNamer struct {
+	strategy string
+	template *template.Template
+}
+
+// nameTemplateData is the data available to a name template
+type nameTemplateData struct {
+	Name      string
+	Namespace string
+}
+
+func buildAssemblyNamer(strategy string, nameTemplate string) (*assemblyNamer, error) {
+	namer := &assemblyNamer{strategy: strategy}
+	switch strategy {
+	case NamingStrategies.Plain, NamingStrategies.NamespacePrefixed:
+	case NamingStrategies.Template:
+		if nameTemplate == "" {
+			return nil, fmt.Errorf("A name template must be set to use the %s naming strategy", strategy)
+		}
+		tmpl, err := template.New("assemblyName").Option("missingkey=error").Parse(nameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid name template %q: %s", nameTemplate, err)
+		}
+		namer.template = tmpl
+	default:
+		return nil, fmt.Errorf("Unsupported naming strategy %q, must be one of %s, %s or %s", strategy, NamingStrategies.Plain, NamingStrategies.NamespacePrefixed, NamingStrategies.Template)
+	}
+	return namer, nil
+}
+
+func (namer *assemblyNamer) name(instance *stratossv1alpha1.Assembly) (string, error) {
+	if instance.Spec.AssemblyName != "" {
+		return instance.Spec.AssemblyName, nil
+	}
+	switch namer.strategy {
+	case NamingStrategies.NamespacePrefixed:
+		// Namespaces cannot contain a ".", so the name is unique for each resource
+		return fmt.Sprintf("%s.%s", instance.Namespace, instance.Name), nil
+	case NamingStrategies.Template:
+		var name bytes.Buffer
+		if err := namer.template.Execute(&name, nameTemplateData{Name: instance.Name, Namespace: instance.Namespace}); err != nil {
+			return "", fmt.Errorf("Unable to produce Assembly name from template: %s", err)
+		}
+		if name.Len() == 0 {
+			return "", fmt.Errorf("Name template produced an empty Assembly name")
+		}
+		return name.String(), nil
+	}
+	return instance.Name, nil
+}
+
+// resolveAssemblyName determines the name of the Assembly in LM and records it on the status, after which it never changes
+func (sync *AssemblySynchronizer) resolveAssemblyName() (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	recordedName := k8sInstance.Status.AssemblyName
+	if recordedName == "" {
+		if k8sInstance.Status.ID != "" {
+			// Created before the name was recorded, when the resource name was always used
+			recordedName = k8sInstance.Name
+		} else {
+			name, err := sync.namer.name(k8sInstance)
+			if err != nil {
+				sync.logger.Error(err, "Failed to determine Assembly name")
+				return sync.onLMError(err)
+			}
+			recordedName = name
+		}
+		k8sInstance.Status.AssemblyName = recordedName
+		sync.needsStatusUpdate = true
+	}
+	if k8sInstance.Spec.AssemblyName != "" && k8sInstance.Spec.AssemblyName != recordedName && k8sInstance.GetDeletionTimestamp() == nil {
+		err := fmt.Errorf("spec.assemblyName cannot be changed from %s to %s", recordedName, k8sInstance.Spec.AssemblyName)
+		sync.logger.Error(err, "Invalid Assembly name")
+		return sync.onLMError(err)
+	}
+	if !sync.loggerHasAssemblyName {
+		sync.logger = sync.logger.WithValues(LogKeys.LMAssemblyName, recordedName)
+		sync.loggerHasAssemblyName = true
+	}
+	return false
+}
+
+// assemblyName returns the name of the Assembly in LM
+func (sync *AssemblySynchronizer) assemblyName() string {
+	return sync.k8sInstance.Status.AssemblyName
+}
@@ -0,0 +1,131 @@
+package assembly
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestBuildAssemblyNamer(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     string
+		nameTemplate string
+		expectErr    bool
+	}{
+		{name: "plain", strategy: NamingStrategies.Plain},
+		{name: "namespace-prefixed", strategy: NamingStrategies.NamespacePrefixed},
+		{name: "template", strategy: NamingStrategies.Template, nameTemplate: "{{ .Namespace }}-{{ .Name }}"},
+		{name: "template not set", strategy: NamingStrategies.Template, expectErr: true},
+		{name: "invalid template", strategy: NamingStrategies.Template, nameTemplate: "{{ .Name", expectErr: true},
+		{name: "unsupported strategy", strategy: "hashed", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildAssemblyNamer(tt.strategy, tt.nameTemplate)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected strategy %q with template %q to be rejected", tt.strategy, tt.nameTemplate)
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("Expected strategy %q with template %q to be accepted: %s", tt.strategy, tt.nameTemplate, err)
+			}
+		})
+	}
+}
+
+func TestAssemblyNamerName(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     string
+		nameTemplate string
+		assemblyName string
+		expectName   string
+		expectErr    bool
+	}{
+		{name: "plain", strategy: NamingStrategies.Plain, expectName: "a1"},
+		{name: "namespace-prefixed", strategy: NamingStrategies.NamespacePrefixed, expectName: "test.a1"},
+		{name: "template", strategy: NamingStrategies.Template, nameTemplate: "cluster1.{{ .Namespace }}.{{ .Name }}", expectName: "cluster1.test.a1"},
+		{name: "spec.assemblyName", strategy: NamingStrategies.NamespacePrefixed, assemblyName: "MyAssembly", expectName: "MyAssembly"},
+		{name: "template with unknown field", strategy: NamingStrategies.Template, nameTemplate: "{{ .Cluster }}.{{ .Name }}", expectErr: true},
+		{name: "template producing empty name", strategy: NamingStrategies.Template, nameTemplate: "{{ if false }}{{ .Name }}{{ end }}", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namer, err := buildAssemblyNamer(tt.strategy, tt.nameTemplate)
+			if err != nil {
+				t.Fatalf("Failed to build namer: %s", err)
+			}
+			spec := activeSpec()
+			spec.AssemblyName = tt.assemblyName
+			name, err := namer.name(newTestAssembly("a1", spec))
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected the name to be rejected but was %q", name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to name Assembly: %s", err)
+			}
+			if name != tt.expectName {
+				t.Errorf("Expected name %q but was %q", tt.expectName, name)
+			}
+		})
+	}
+}
+
+func TestReconcileKeepsNameOfAssemblyCreatedBeforeNameRecorded(t *testing.T) {
+	instance := newTestAssembly("a1", activeSpec())
+	env := newTestEnv(t, instance)
+	defer env.close()
+	instance.Status.ID = env.lm.AddAssembly(lm.Assembly{
+		Name:           "a1",
+		State:          stratossv1alpha1.AssemblyStates.Active,
+		DescriptorName: "assembly::test::1.0",
+		Properties:     []lm.AssemblyProperty{{Name: "size", Value: "small"}},
+	})
+	if err := env.k8sClient.Status().Update(context.TODO(), instance); err != nil {
+		t.Fatalf("Failed to update status: %s", err)
+	}
+	namer, err := buildAssemblyNamer(NamingStrategies.NamespacePrefixed, "")
+	if err != nil {
+		t.Fatalf("Failed to build namer: %s", err)
+	}
+	env.reconciler.namer = namer
+
+	env.reconcileUntil("a1", isActive)
+	if name := env.get("a1").Status.AssemblyName; name != "a1" {
+		t.Errorf("Expected the resource name to be kept for an Assembly created before the name was recorded but was %q", name)
+	}
+	if requests := env.intentRequests(); requests != 0 {
+		t.Errorf("Expected the existing Assembly to be used but found %d intent requests", requests)
+	}
+}
+
+func TestReconcileRejectsChangedAssemblyName(t *testing.T) {
+	spec := activeSpec()
+	spec.AssemblyName = "first"
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	requests := env.intentRequests()
+
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.AssemblyName = "second" })
+	if _, err := env.reconcile("a1"); err == nil || !strings.Contains(err.Error(), "spec.assemblyName") {
+		t.Fatalf("Expected the reconcile to report the change of spec.assemblyName but error was %v", err)
+	}
+	instance := env.get("a1")
+	if instance.Status.AssemblyName != "first" {
+		t.Errorf("Expected the recorded name to be kept but was %q", instance.Status.AssemblyName)
+	}
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+		t.Errorf("Expected Synced to be False (%s) but was %s (%s)", ConditionReasons.SyncError, status, reason)
+	}
+	if actual := env.intentRequests(); actual != requests {
+		t.Errorf("Expected no intents once spec.assemblyName is changed but found %d more", actual-requests)
+	}
+}
@@ -0,0 +1,97 @@
+package assembly
+
+import (
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// operatorAnnotationPrefix is shared by the annotations which change how the operator manages an Assembly
+const operatorAnnotationPrefix = "stratoss.accantosystems.com/"
+
+// unchangedSinceLastSync returns true when nothing which decides what the operator does with the Assembly has changed since a reconcile
+// left nothing to do and a resync with LM is not yet due, so the reconcile can be skipped. The remaining time is how long until the resync is due,
+// or the maintenance window recorded on the status opens or closes if that is sooner
+func (sync *AssemblySynchronizer) unchangedSinceLastSync() (remaining time.Duration, unchanged bool) {
+	k8sInstance := sync.k8sInstance
+	status := k8sInstance.Status
+	if k8sInstance.GetDeletionTimestamp() != nil || status.LastSyncHash == "" || status.LastSyncTime == nil {
+		return 0, false
+	}
+	if status.ObservedGeneration != k8sInstance.GetGeneration() || status.SyncState.Status != "OK" {
+		return 0, false
+	}
+	if interval, enabled := sync.poller.resyncInterval(k8sInstance); enabled {
+		remaining = time.Until(status.LastSyncTime.Add(interval))
+		if remaining <= 0 {
+			return 0, false
+		}
+	}
+	if untilWindowChanges, found := untilMaintenanceWindowChanges(k8sInstance, time.Now()); found {
+		// The maintenance window on the status is resolved again once it opens or closes, even when resyncs are disabled
+		if untilWindowChanges <= 0 {
+			return 0, false
+		}
+		if remaining == 0 || untilWindowChanges < remaining {
+			remaining = untilWindowChanges
+		}
+	}
+	if _, err := sync.resolvePropertyValues(); err != nil {
+		// Left to the full reconcile to report
+		return 0, false
+	}
+	outputVersions, found := sync.readOutputVersions()
+	if !found {
+		return 0, false
+	}
+	sync.outputVersions = outputVersions
+	if sync.reconcileInputsHash() != status.LastSyncHash {
+		return 0, false
+	}
+	return remaining, true
+}
+
+// recordSyncBookkeeping records on the status how far this reconcile got with the latest spec
+func (sync *AssemblySynchronizer) recordSyncBookkeeping(lastError error) {
+	k8sInstance := sync.k8sInstance
+	k8sInstance.Status.LastSyncHash = ""
+	if lastError != nil {
+		return
+	}
+	now := metav1.Now()
+	k8sInstance.Status.LastSyncTime = &now
+	if sync.specObserved {
+		k8sInstance.Status.ObservedGeneration = k8sInstance.GetGeneration()
+		if !sync.intentPlanned && sync.planOutdated == "" {
+			// Nothing would be requested now, or the planned intent was requested, so the plan no longer applies
+			k8sInstance.Status.PlannedIntent = nil
+		}
+		if !sync.approvalPending {
+			k8sInstance.Status.PendingApproval = nil
+		}
+	}
+	if sync.inSync && !sync.requeue {
+		k8sInstance.Status.LastSyncHash = sync.reconcileInputsHash()
+	}
+	sync.needsStatusUpdate = true
+}
+
+// recordIntent records when, and from which spec, an intent was requested
+func (sync *AssemblySynchronizer) recordIntent() {
+	now := metav1.Now()
+	sync.k8sInstance.Status.LastIntentTime = &now
+	sync.k8sInstance.Status.LastIntentSpecHash = specHash(sync.k8sInstance.Spec)
+}
+
+// needsReconcile filters out updates to an Assembly which only change its status, including those made by the operator itself
+func needsReconcile(e event.UpdateEvent) bool {
+	if e.MetaOld == nil || e.MetaNew == nil {
+		return true
+	}
+	return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+		!reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations()) ||
+		!reflect.DeepEqual(e.MetaOld.GetFinalizers(), e.MetaNew.GetFinalizers()) ||
+		(e.MetaOld.GetDeletionTimestamp() == nil) != (e.MetaNew.GetDeletionTimestamp() == nil)
+}
@@ -0,0 +1,197 @@
+package assembly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// inSyncSpec reads a property from a Secret and publishes an output, so every input to the skip is in use
+func inSyncSpec() stratossv1alpha1.AssemblySpec {
+	spec := activeSpec()
+	spec.PropertiesFrom = []stratossv1alpha1.PropertySource{
+		{Name: "password", ValueFrom: stratossv1alpha1.PropertyValueSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+			Key:                  "password",
+		}}},
+	}
+	spec.Outputs = []stratossv1alpha1.Output{{Name: "a1-outputs", Properties: []stratossv1alpha1.OutputProperty{{Name: "size"}}}}
+	return spec
+}
+
+func newCredentialsSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "creds"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+}
+
+func TestReconcileSkipsUnchangedAssembly(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func(env *testEnv)
+		expectSkip bool
+	}{
+		{name: "unchanged", change: func(env *testEnv) {}, expectSkip: true},
+		{
+			name: "status changed",
+			change: func(env *testEnv) {
+				instance := env.get("a1")
+				instance.Status.DescriptorName = "assembly::test::0.1"
+				if err := env.k8sClient.Status().Update(context.TODO(), instance); err != nil {
+					t.Fatalf("Failed to update status: %s", err)
+				}
+			},
+			expectSkip: true,
+		},
+		{
+			name: "other annotation changed",
+			change: func(env *testEnv) {
+				env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+					instance.SetAnnotations(map[string]string{"example.com/note": "changed"})
+				})
+			},
+			expectSkip: true,
+		},
+		{
+			name: "spec changed",
+			change: func(env *testEnv) {
+				env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "large" })
+			},
+		},
+		{
+			name: "operator annotation changed",
+			change: func(env *testEnv) {
+				env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+					instance.SetAnnotations(map[string]string{stratossv1alpha1.RetryAnnotation: "1"})
+				})
+			},
+		},
+		{
+			name: "referenced Secret changed",
+			change: func(env *testEnv) {
+				secret := &corev1.Secret{}
+				if err := env.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "creds"}, secret); err != nil {
+					t.Fatalf("Failed to get Secret: %s", err)
+				}
+				secret.Data["password"] = []byte("rotated")
+				if err := env.k8sClient.Update(context.TODO(), secret); err != nil {
+					t.Fatalf("Failed to update Secret: %s", err)
+				}
+			},
+		},
+		{
+			name: "output changed by someone else",
+			change: func(env *testEnv) {
+				configMap := &corev1.ConfigMap{}
+				if err := env.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "a1-outputs"}, configMap); err != nil {
+					t.Fatalf("Failed to get output: %s", err)
+				}
+				configMap.Data["size"] = "tampered"
+				if err := env.k8sClient.Update(context.TODO(), configMap); err != nil {
+					t.Fatalf("Failed to update output: %s", err)
+				}
+			},
+		},
+		{
+			name: "resync due",
+			change: func(env *testEnv) {
+				instance := env.get("a1")
+				lastSyncTime := metav1.NewTime(time.Now().Add(-testPollSettings.resyncPeriod))
+				instance.Status.LastSyncTime = &lastSyncTime
+				if err := env.k8sClient.Status().Update(context.TODO(), instance); err != nil {
+					t.Fatalf("Failed to update status: %s", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, newTestAssembly("a1", inSyncSpec()), newCredentialsSecret())
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				return instance.Status.LastSyncHash != ""
+			})
+
+			tt.change(env)
+			requests := len(env.lm.Requests())
+			result := env.mustReconcile("a1")
+			skipped := len(env.lm.Requests()) == requests
+			if skipped != tt.expectSkip {
+				t.Errorf("Expected reconcile skipped to be %t but LM was called %d time(s)", tt.expectSkip, len(env.lm.Requests())-requests)
+			}
+			if tt.expectSkip && (result.RequeueAfter <= 0 || result.RequeueAfter > testPollSettings.resyncPeriod) {
+				t.Errorf("Expected a skipped reconcile to be requeued when the resync is due but was %+v", result)
+			}
+		})
+	}
+}
+
+func TestReconcileRecordsBookkeeping(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.completeImmediately()
+
+	env.mustReconcile("a1")
+	instance := env.get("a1")
+	if instance.Status.LastIntentTime == nil || instance.Status.LastIntentSpecHash != specHash(instance.Spec) {
+		t.Errorf("Expected the Create intent to be recorded but last intent was %v with spec hash %s", instance.Status.LastIntentTime, instance.Status.LastIntentSpecHash)
+	}
+	env.reconcileUntil("a1", isActive)
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "large" })
+	if instance := env.get("a1"); instance.Status.ObservedGeneration != 1 {
+		t.Errorf("Expected observed generation 1 before the change is reconciled but was %d", instance.Status.ObservedGeneration)
+	}
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.ObservedGeneration == 2 && instance.Status.LastSyncHash != ""
+	})
+	if instance := env.get("a1"); instance.Status.LastSyncTime == nil || instance.Status.LastIntentSpecHash != specHash(instance.Spec) {
+		t.Errorf("Expected the Update intent to be recorded but status is %+v", instance.Status)
+	}
+}
+
+func TestNeedsReconcile(t *testing.T) {
+	base := newTestAssembly("a1", activeSpec())
+	tests := []struct {
+		name     string
+		change   func(instance *stratossv1alpha1.Assembly)
+		expected bool
+	}{
+		{name: "status only", change: func(instance *stratossv1alpha1.Assembly) { instance.Status.State = "Active" }},
+		{name: "generation", change: func(instance *stratossv1alpha1.Assembly) { instance.Generation++ }, expected: true},
+		{
+			name:     "annotation",
+			change:   func(instance *stratossv1alpha1.Assembly) { instance.SetAnnotations(map[string]string{"a": "b"}) },
+			expected: true,
+		},
+		{
+			name:     "finalizer",
+			change:   func(instance *stratossv1alpha1.Assembly) { instance.SetFinalizers([]string{assemblyFinalizer}) },
+			expected: true,
+		},
+		{
+			name: "deletion",
+			change: func(instance *stratossv1alpha1.Assembly) {
+				now := metav1.Now()
+				instance.SetDeletionTimestamp(&now)
+			},
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.DeepCopy()
+			tt.change(updated)
+			if actual := needsReconcile(event.UpdateEvent{MetaOld: base, MetaNew: updated}); actual != tt.expected {
+				t.Errorf("Expected needsReconcile to be %t but was %t", tt.expected, actual)
+			}
+		})
+	}
+}
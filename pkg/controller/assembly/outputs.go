@@ -0,0 +1,179 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// outputOwnerLabel is set on the ConfigMaps and Secrets outputs are published to, with the UID of the Assembly, so they can be found without
+// listing every ConfigMap and Secret in the namespace
+const outputOwnerLabel = "stratoss.accantosystems.com/assembly-uid"
+
+func outputKind(output stratossv1alpha1.Output) string {
+	if output.Kind == "" {
+		return stratossv1alpha1.OutputKinds.ConfigMap
+	}
+	return output.Kind
+}
+
+func newOutputObject(output stratossv1alpha1.Output, namespace string) runtime.Object {
+	meta := metav1.ObjectMeta{Namespace: namespace, Name: output.Name}
+	if outputKind(output) == stratossv1alpha1.OutputKinds.Secret {
+		return &corev1.Secret{ObjectMeta: meta}
+	}
+	return &corev1.ConfigMap{ObjectMeta: meta}
+}
+
+// outputValues returns the values to publish to an output, keyed by the key each is published under
+func (sync *AssemblySynchronizer) outputValues(output stratossv1alpha1.Output, lmProperties map[string]string) (map[string]string, error) {
+	secretProperties := secretPropertyNames(sync.k8sInstance.Spec)
+	values := make(map[string]string)
+	for _, property := range output.Properties {
+		if secretProperties[property.Name] && outputKind(output) != stratossv1alpha1.OutputKinds.Secret {
+			return nil, fmt.Errorf("Property %s is read from a Secret so can only be published to a Secret, not %s %s", property.Name, outputKind(output), output.Name)
+		}
+		value, ok := lmProperties[property.Name]
+		if !ok {
+			sync.logger.Info("Property not set on Assembly in LM, leaving it out of output", LogKeys.PropertyName, property.Name, LogKeys.Output, output.Name)
+			continue
+		}
+		key := property.Key
+		if key == "" {
+			key = property.Name
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// publishOutputs creates or updates the ConfigMaps and Secrets listed in spec.outputs with the properties of the Assembly in LM,
+// and removes those owned by the Assembly which are no longer listed
+func (sync *AssemblySynchronizer) publishOutputs() (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	sync.outputVersions = nil
+	if k8sInstance.GetDeletionTimestamp() != nil || sync.adoptionConflict != "" || !sync.lmSourceOfTruth.assemblyInstanceFound {
+		// Outputs are garbage collected with the Assembly. Until it exists in LM there is nothing to publish
+		return false
+	}
+	lmProperties := sync.lmSourceOfTruth.assemblyProperties()
+	for _, output := range k8sInstance.Spec.Outputs {
+		values, err := sync.outputValues(output, lmProperties)
+		if err != nil {
+			sync.logger.Error(err, "Invalid Assembly output", LogKeys.Output, output.Name)
+			return sync.onLMError(err)
+		}
+		obj := newOutputObject(output, k8sInstance.Namespace)
+		result, err := controllerutil.CreateOrUpdate(context.TODO(), sync.k8sClient, obj, func() error {
+			return sync.mutateOutput(obj, values)
+		})
+		if err != nil {
+			sync.logger.Error(err, "Failed to publish Assembly output", LogKeys.Output, output.Name)
+			return sync.onUpdateError(err)
+		}
+		if result != controllerutil.OperationResultNone {
+			sync.logger.Info("Published Assembly output", LogKeys.Output, output.Name, LogKeys.OutputKind, outputKind(output), "result", result)
+		}
+		sync.outputVersions = append(sync.outputVersions, outputVersion(obj))
+	}
+	return sync.removeStaleOutputs()
+}
+
+func (sync *AssemblySynchronizer) mutateOutput(obj runtime.Object, values map[string]string) error {
+	k8sInstance := sync.k8sInstance
+	meta := obj.(metav1.Object)
+	if meta.GetResourceVersion() != "" {
+		if owner := metav1.GetControllerOf(meta); owner == nil || owner.UID != k8sInstance.UID {
+			return fmt.Errorf("%s %s already exists and is not owned by this Assembly", outputObjectKind(obj), meta.GetName())
+		}
+	}
+	labels := meta.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[outputOwnerLabel] = string(k8sInstance.UID)
+	meta.SetLabels(labels)
+	switch typed := obj.(type) {
+	case *corev1.Secret:
+		typed.Type = corev1.SecretTypeOpaque
+		typed.Data = make(map[string][]byte, len(values))
+		for key, value := range values {
+			typed.Data[key] = []byte(value)
+		}
+	case *corev1.ConfigMap:
+		typed.Data = values
+		typed.BinaryData = nil
+	}
+	return controllerutil.SetControllerReference(k8sInstance, meta, sync.scheme)
+}
+
+func outputObjectKind(obj runtime.Object) string {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return stratossv1alpha1.OutputKinds.Secret
+	}
+	return stratossv1alpha1.OutputKinds.ConfigMap
+}
+
+func outputVersion(obj runtime.Object) string {
+	meta := obj.(metav1.Object)
+	return fmt.Sprintf("%s/%s@%s", outputObjectKind(obj), meta.GetName(), meta.GetResourceVersion())
+}
+
+// readOutputVersions returns the current versions of the outputs of the Assembly, or false if any of them does not exist
+func (sync *AssemblySynchronizer) readOutputVersions() (versions []string, found bool) {
+	k8sInstance := sync.k8sInstance
+	for _, output := range k8sInstance.Spec.Outputs {
+		obj := newOutputObject(output, k8sInstance.Namespace)
+		if err := sync.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: k8sInstance.Namespace, Name: output.Name}, obj); err != nil {
+			return nil, false
+		}
+		versions = append(versions, outputVersion(obj))
+	}
+	return versions, true
+}
+
+// removeStaleOutputs deletes ConfigMaps and Secrets owned by the Assembly which are no longer listed in spec.outputs. Only those labelled
+// as outputs of the Assembly are considered, and the controller reference is checked as well, as anyone may set the label
+func (sync *AssemblySynchronizer) removeStaleOutputs() (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	listed := make(map[string]bool)
+	for _, output := range k8sInstance.Spec.Outputs {
+		listed[outputKind(output)+"/"+output.Name] = true
+	}
+	configMaps := &corev1.ConfigMapList{}
+	secrets := &corev1.SecretList{}
+	var candidates []runtime.Object
+	for _, list := range []runtime.Object{configMaps, secrets} {
+		if err := sync.k8sClient.List(context.TODO(), list, client.InNamespace(k8sInstance.Namespace), client.MatchingLabels{outputOwnerLabel: string(k8sInstance.UID)}); err != nil {
+			sync.logger.Error(err, "Failed to list Assembly outputs")
+			return sync.onUpdateError(err)
+		}
+	}
+	for i := range configMaps.Items {
+		candidates = append(candidates, &configMaps.Items[i])
+	}
+	for i := range secrets.Items {
+		candidates = append(candidates, &secrets.Items[i])
+	}
+	for _, obj := range candidates {
+		meta := obj.(metav1.Object)
+		owner := metav1.GetControllerOf(meta)
+		if owner == nil || owner.UID != k8sInstance.UID || listed[outputObjectKind(obj)+"/"+meta.GetName()] {
+			continue
+		}
+		sync.logger.Info("Removing output no longer listed on the Assembly", LogKeys.Output, meta.GetName(), LogKeys.OutputKind, outputObjectKind(obj))
+		if err := sync.k8sClient.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+			sync.logger.Error(err, "Failed to remove Assembly output", LogKeys.Output, meta.GetName())
+			return sync.onUpdateError(err)
+		}
+	}
+	return false
+}
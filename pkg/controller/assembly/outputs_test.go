@@ -0,0 +1,150 @@
+package assembly
+
+import (
+	"context"
+	"testing"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func outputSpec(outputs ...stratossv1alpha1.Output) stratossv1alpha1.AssemblySpec {
+	spec := activeSpec()
+	spec.Outputs = outputs
+	return spec
+}
+
+func sizeOutput(kind string, name string) stratossv1alpha1.Output {
+	return stratossv1alpha1.Output{Kind: kind, Name: name, Properties: []stratossv1alpha1.OutputProperty{{Name: "size", Key: "SIZE"}}}
+}
+
+// controlledBy returns an owner reference making the named Assembly the controller of an object
+func controlledBy(name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{
+		APIVersion: stratossv1alpha1.SchemeGroupVersion.String(),
+		Kind:       "Assembly",
+		Name:       name,
+		UID:        types.UID("uid-" + name),
+		Controller: &controller,
+	}}
+}
+
+// getOutput returns the output with the given name, or nil if it does not exist
+func (env *testEnv) getOutput(kind string, name string) metav1.Object {
+	env.t.Helper()
+	var obj runtime.Object = &corev1.ConfigMap{}
+	if kind == stratossv1alpha1.OutputKinds.Secret {
+		obj = &corev1.Secret{}
+	}
+	if err := env.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: name}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		env.t.Fatalf("Failed to get %s %s: %s", kind, name, err)
+	}
+	return obj.(metav1.Object)
+}
+
+func TestPublishOutputs(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", outputSpec(sizeOutput("", "a1-config"), sizeOutput(stratossv1alpha1.OutputKinds.Secret, "a1-secret"))))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	env.mustReconcile("a1")
+
+	configMap, ok := env.getOutput(stratossv1alpha1.OutputKinds.ConfigMap, "a1-config").(*corev1.ConfigMap)
+	if !ok || configMap.Data["SIZE"] != "small" {
+		t.Fatalf("Expected the property to be published to the ConfigMap but was %+v", configMap)
+	}
+	secret, ok := env.getOutput(stratossv1alpha1.OutputKinds.Secret, "a1-secret").(*corev1.Secret)
+	if !ok || string(secret.Data["SIZE"]) != "small" {
+		t.Fatalf("Expected the property to be published to the Secret but was %+v", secret)
+	}
+	for _, obj := range []metav1.Object{configMap, secret} {
+		if owner := metav1.GetControllerOf(obj); owner == nil || owner.UID != "uid-a1" {
+			t.Errorf("Expected %s to be controlled by the Assembly but owner is %+v", obj.GetName(), owner)
+		}
+		if label := obj.GetLabels()[outputOwnerLabel]; label != "uid-a1" {
+			t.Errorf("Expected %s to be labelled with the UID of the Assembly but label was %q", obj.GetName(), label)
+		}
+	}
+}
+
+func TestOutputOwnershipConflict(t *testing.T) {
+	// The resource version is set as it would be by the API server, which is how an output which already exists is recognised
+	tests := []struct {
+		name     string
+		existing *corev1.ConfigMap
+	}{
+		{
+			name: "not owned",
+			existing: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "shared", ResourceVersion: "1"},
+				Data:       map[string]string{"SIZE": "mine"},
+			},
+		},
+		{
+			name: "owned by another Assembly",
+			existing: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "shared", ResourceVersion: "1", OwnerReferences: controlledBy("other")},
+				Data:       map[string]string{"SIZE": "mine"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, newTestAssembly("a1", outputSpec(sizeOutput("", "shared"))), tt.existing)
+			defer env.close()
+			env.completeImmediately()
+			// Published once the Assembly exists in LM, after the reconcile which creates it
+			env.mustReconcile("a1")
+			if _, err := env.reconcile("a1"); err == nil {
+				t.Errorf("Expected publishing to a ConfigMap owned by someone else to fail the reconcile")
+			}
+
+			instance := env.get("a1")
+			if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+				t.Errorf("Expected Synced to be False (%s) but was %s (%s)", ConditionReasons.SyncError, status, reason)
+			}
+			configMap := env.getOutput(stratossv1alpha1.OutputKinds.ConfigMap, "shared").(*corev1.ConfigMap)
+			if configMap.Data["SIZE"] != "mine" || configMap.GetLabels()[outputOwnerLabel] != "" {
+				t.Errorf("Expected the existing ConfigMap to be left untouched but was %+v", configMap)
+			}
+		})
+	}
+}
+
+func TestRemoveStaleOutputs(t *testing.T) {
+	labels := map[string]string{outputOwnerLabel: "uid-a1"}
+	labelledOnly := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "labelled-only", Labels: labels}}
+	otherOutput := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "other-output", OwnerReferences: controlledBy("other")}}
+	env := newTestEnv(t, newTestAssembly("a1", outputSpec(sizeOutput("", "a1-keep"), sizeOutput("", "a1-stale"), sizeOutput(stratossv1alpha1.OutputKinds.Secret, "a1-stale-secret"))), labelledOnly, otherOutput)
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	env.mustReconcile("a1")
+	if env.getOutput(stratossv1alpha1.OutputKinds.ConfigMap, "a1-stale") == nil || env.getOutput(stratossv1alpha1.OutputKinds.Secret, "a1-stale-secret") == nil {
+		t.Fatalf("Expected every output to be published before any are removed from the spec")
+	}
+
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+		instance.Spec.Outputs = []stratossv1alpha1.Output{sizeOutput("", "a1-keep")}
+	})
+	env.mustReconcile("a1")
+	if env.getOutput(stratossv1alpha1.OutputKinds.ConfigMap, "a1-stale") != nil {
+		t.Errorf("Expected the ConfigMap no longer listed to be removed")
+	}
+	if env.getOutput(stratossv1alpha1.OutputKinds.Secret, "a1-stale-secret") != nil {
+		t.Errorf("Expected the Secret no longer listed to be removed")
+	}
+	for _, name := range []string{"a1-keep", "labelled-only", "other-output"} {
+		if env.getOutput(stratossv1alpha1.OutputKinds.ConfigMap, name) == nil {
+			t.Errorf("Expected ConfigMap %s to be kept", name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package assembly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+)
+
+// plannedBy returns what put the Assembly in Plan mode, or an empty string if intents are applied
+func plannedBy(instance *stratossv1alpha1.Assembly) string {
+	if instance.GetAnnotations()[stratossv1alpha1.DryRunAnnotation] == "true" {
+		return "annotation " + stratossv1alpha1.DryRunAnnotation
+	}
+	if instance.Spec.Mode == stratossv1alpha1.Modes.Plan {
+		return "spec.mode"
+	}
+	return ""
+}
+
+// redactProperties returns a copy of properties safe to be shown in status or logs
+func (sync *AssemblySynchronizer) redactProperties(properties map[string]string) map[string]string {
+	redacted := make(map[string]string, len(properties))
+	for name, value := range properties {
+		redacted[name] = sync.redactProperty(name, value)
+	}
+	return redacted
+}
+
+// redactRequest returns a copy of an intent request with the values of properties read from Secrets redacted
+func (sync *AssemblySynchronizer) redactRequest(request interface{}) interface{} {
+	switch typed := request.(type) {
+	case lm.CreateAssemblyRequest:
+		typed.Properties = sync.redactProperties(typed.Properties)
+		return typed
+	case lm.UpgradeAssemblyRequest:
+		typed.Properties = sync.redactProperties(typed.Properties)
+		return typed
+	}
+	return request
+}
+
+// planIntent records the intent which would be requested in place of requesting it, when the Assembly is in Plan mode
+func (sync *AssemblySynchronizer) planIntent(intentType string, request interface{}) (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	// Encoded without escaping, so the redacted marker reads as it does elsewhere in the status
+	payload := &bytes.Buffer{}
+	encoder := json.NewEncoder(payload)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(sync.redactRequest(request)); err != nil {
+		return sync.onLMError(fmt.Errorf("Unable to plan %s intent: %s", intentType, err))
+	}
+	planned := &stratossv1alpha1.PlannedIntent{
+		IntentType: intentType,
+		Request:    strings.TrimSpace(payload.String()),
+		SpecHash:   sync.desiredStateHash(),
+	}
+	sync.logger.Info("Assembly is in Plan mode, recording intent without requesting it", "intentType", intentType, "request", planned.Request)
+	if previous := k8sInstance.Status.PlannedIntent; previous == nil || *previous != *planned {
+		sync.recordNormalEvent(EventReasons.IntentPlanned, "%s planned but not requested whilst in Plan mode (set by %s): %s", intentType, plannedBy(k8sInstance), planned.Request)
+	}
+	k8sInstance.Status.PlannedIntent = planned
+	sync.intentPlanned = true
+	sync.needsStatusUpdate = true
+	sync.stopSync = true
+	return sync.stopSync
+}
+
+// checkPlan refuses to request an intent in Apply mode when an intent planned in Plan mode is still recorded, but was planned for a different
+// desired state or is not the intent now needed, so a plan which was reviewed is never followed by a different change to LM
+func (sync *AssemblySynchronizer) checkPlan(intentType string) (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	planned := k8sInstance.Status.PlannedIntent
+	if planned == nil {
+		return false
+	}
+	currentHash := sync.desiredStateHash()
+	if planned.IntentType == intentType && planned.SpecHash == currentHash {
+		sync.logger.Info("Requesting intent planned in Plan mode", "intentType", intentType)
+		return false
+	}
+	if planned.IntentType != intentType {
+		sync.planOutdated = fmt.Sprintf("%s was planned but %s is now needed, switch to Plan mode to review the new plan before applying it", planned.IntentType, intentType)
+	} else {
+		sync.planOutdated = fmt.Sprintf("%s was planned for desired state %s but the desired state is now %s, switch to Plan mode to review the new plan before applying it", intentType, planned.SpecHash, currentHash)
+	}
+	sync.logger.Info("Intent planned in Plan mode no longer matches the Assembly, not requesting it", "reason", sync.planOutdated)
+	if ready := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Ready); ready == nil || ready.Reason != ConditionReasons.PlanOutdated {
+		sync.recordWarningEvent(EventReasons.PlanOutdated, "%s", sync.planOutdated)
+	}
+	sync.needsStatusUpdate = true
+	sync.stopSync = true
+	return sync.stopSync
+}
@@ -0,0 +1,199 @@
+package assembly
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// intentRequests returns the number of intents sent to LM, of any type
+func (env *testEnv) intentRequests() int {
+	count := 0
+	for _, request := range env.lm.Requests() {
+		if request.Method == http.MethodPost && strings.HasPrefix(request.Path, "/api/intent/") {
+			count++
+		}
+	}
+	return count
+}
+
+func setPlanMode(instance *stratossv1alpha1.Assembly) {
+	instance.Spec.Mode = stratossv1alpha1.Modes.Plan
+}
+
+func setApplyMode(instance *stratossv1alpha1.Assembly) {
+	instance.Spec.Mode = stratossv1alpha1.Modes.Apply
+}
+
+func TestPlanModeSendsNoIntents(t *testing.T) {
+	tests := []struct {
+		name         string
+		plan         func(instance *stratossv1alpha1.Assembly)
+		change       func(env *testEnv)
+		expectIntent string
+	}{
+		{
+			name:         "create",
+			change:       func(env *testEnv) {},
+			expectIntent: "Create",
+		},
+		{
+			name: "update",
+			change: func(env *testEnv) {
+				env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "large" })
+			},
+			expectIntent: "Update",
+		},
+		{
+			name: "change state",
+			change: func(env *testEnv) {
+				env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+					instance.Spec.IntendedState = stratossv1alpha1.AssemblyStates.Inactive
+				})
+			},
+			expectIntent: "ChangeState",
+		},
+		{
+			name:         "delete",
+			change:       func(env *testEnv) { env.markDeleted("a1") },
+			expectIntent: "Delete",
+		},
+		{
+			name: "update with dry-run annotation",
+			plan: func(instance *stratossv1alpha1.Assembly) {
+				instance.SetAnnotations(map[string]string{stratossv1alpha1.DryRunAnnotation: "true"})
+			},
+			change: func(env *testEnv) {
+				env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.DescriptorName = "assembly::test::2.0" })
+			},
+			expectIntent: "Update",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+			defer env.close()
+			env.completeImmediately()
+			plan := tt.plan
+			if plan == nil {
+				plan = setPlanMode
+			}
+			if tt.expectIntent != "Create" {
+				env.reconcileUntil("a1", isActive)
+			}
+			env.edit("a1", plan)
+			intents := env.intentRequests()
+			env.events()
+
+			tt.change(env)
+			for i := 0; i < 3; i++ {
+				env.mustReconcile("a1")
+			}
+			if sent := env.intentRequests() - intents; sent != 0 {
+				t.Errorf("Expected no intents to be sent to LM in Plan mode but %d were sent", sent)
+			}
+			instance := env.get("a1")
+			if planned := instance.Status.PlannedIntent; planned == nil || planned.IntentType != tt.expectIntent {
+				t.Fatalf("Expected a planned %s intent but was %+v", tt.expectIntent, planned)
+			}
+			if events := env.events(); !hasEvent(events, EventReasons.IntentPlanned) {
+				t.Errorf("Expected an %s event but found %v", EventReasons.IntentPlanned, events)
+			}
+			if tt.expectIntent == "Delete" && !finalizerContains(instance.GetFinalizers(), assemblyFinalizer) {
+				t.Errorf("Expected the finalizer to be kept whilst the Delete is only planned")
+			}
+		})
+	}
+}
+
+func TestApplyRequestsPlannedIntent(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	env.edit("a1", setPlanMode)
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "large" })
+	env.mustReconcile("a1")
+
+	env.edit("a1", setApplyMode)
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return instance.Status.Properties["size"] == "large" && isActive(instance)
+	})
+	if requests := env.lm.IntentRequests("upgradeAssembly"); len(requests) != 1 {
+		t.Errorf("Expected the planned Update to be requested once but found %d upgradeAssembly requests", len(requests))
+	}
+	if planned := env.get("a1").Status.PlannedIntent; planned != nil {
+		t.Errorf("Expected the plan to be cleared once requested but was %+v", planned)
+	}
+}
+
+func TestApplyRefusesOutdatedPlan(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(instance *stratossv1alpha1.Assembly)
+	}{
+		{
+			name:   "properties changed",
+			change: func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "huge" },
+		},
+		{
+			name: "different intent needed",
+			change: func(instance *stratossv1alpha1.Assembly) {
+				instance.Spec.IntendedState = stratossv1alpha1.AssemblyStates.Inactive
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", isActive)
+			env.edit("a1", setPlanMode)
+			env.edit("a1", func(instance *stratossv1alpha1.Assembly) { instance.Spec.Properties["size"] = "large" })
+			env.mustReconcile("a1")
+			planned := env.get("a1").Status.PlannedIntent
+			intents := env.intentRequests()
+			env.events()
+
+			// Changed after the plan was reviewed, in the same edit which switches back to Apply
+			env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+				setApplyMode(instance)
+				tt.change(instance)
+			})
+			env.mustReconcile("a1")
+			env.mustReconcile("a1")
+			if sent := env.intentRequests() - intents; sent != 0 {
+				t.Errorf("Expected no intents to be sent for an outdated plan but %d were sent", sent)
+			}
+			instance := env.get("a1")
+			if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Ready); status != corev1.ConditionFalse || reason != ConditionReasons.PlanOutdated {
+				t.Errorf("Expected Ready to be False (%s) but was %s (%s)", ConditionReasons.PlanOutdated, status, reason)
+			}
+			if instance.Status.PlannedIntent == nil || *instance.Status.PlannedIntent != *planned {
+				t.Errorf("Expected the reviewed plan to be kept but was %+v", instance.Status.PlannedIntent)
+			}
+			warnings := 0
+			for _, event := range env.events() {
+				if strings.Contains(event, EventReasons.PlanOutdated) {
+					warnings++
+				}
+			}
+			if warnings != 1 {
+				t.Errorf("Expected a single %s event but found %d", EventReasons.PlanOutdated, warnings)
+			}
+
+			// Planned again, reviewed and applied
+			env.edit("a1", setPlanMode)
+			env.mustReconcile("a1")
+			env.edit("a1", setApplyMode)
+			env.mustReconcile("a1")
+			if sent := env.intentRequests() - intents; sent != 1 {
+				t.Errorf("Expected the new plan to be requested once applied but %d intents were sent", sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package assembly
+
+import (
+	"fmt"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// deletionPolicy returns the deletion policy of the Assembly, with the annotation taking precedence over the spec
+func deletionPolicy(instance *stratossv1alpha1.Assembly) (string, error) {
+	if policy, ok := instance.GetAnnotations()[stratossv1alpha1.DeletionPolicyAnnotation]; ok {
+		switch policy {
+		case stratossv1alpha1.DeletionPolicies.Delete, stratossv1alpha1.DeletionPolicies.Orphan:
+			return policy, nil
+		}
+		// Never fall back to Delete on an invalid value, as the user clearly intended to override the policy
+		return "", fmt.Errorf("Invalid value %q for annotation %s, must be %s or %s", policy, stratossv1alpha1.DeletionPolicyAnnotation, stratossv1alpha1.DeletionPolicies.Delete, stratossv1alpha1.DeletionPolicies.Orphan)
+	}
+	if instance.Spec.DeletionPolicy == "" {
+		return stratossv1alpha1.DeletionPolicies.Delete, nil
+	}
+	return instance.Spec.DeletionPolicy, nil
+}
+
+// checkAdoption decides whether an Assembly found by name in LM, which this resource has no record of, may be managed by it.
+// Returns a message describing the conflict when it must be left untouched
+func (sync *AssemblySynchronizer) checkAdoption(lmSourceOfTruth *LMSourceOfTruth) (conflict string) {
+	k8sInstance := sync.k8sInstance
+	if k8sInstance.Status.ID != "" || !lmSourceOfTruth.assemblyInstanceFound {
+		return ""
+	}
+	assemblyInstance := lmSourceOfTruth.assemblyInstance
+	if lmSourceOfTruth.trackedProcessFound && lmSourceOfTruth.trackedProcess.AssemblyID == assemblyInstance.ID {
+		// Created by the operator for this resource
+		return ""
+	}
+	policy := k8sInstance.Spec.AdoptionPolicy
+	if policy == "" {
+		policy = stratossv1alpha1.AdoptionPolicies.IfMatching
+	}
+	switch policy {
+	case stratossv1alpha1.AdoptionPolicies.Always:
+	case stratossv1alpha1.AdoptionPolicies.IfMatching:
+		if assemblyInstance.DescriptorName != k8sInstance.Spec.DescriptorName {
+			return fmt.Sprintf("Assembly %s already exists in LM with descriptor %s, which does not match %s (adoptionPolicy %s)", assemblyInstance.Name, assemblyInstance.DescriptorName, k8sInstance.Spec.DescriptorName, policy)
+		}
+	default:
+		return fmt.Sprintf("Assembly %s already exists in LM and was not created by this resource (adoptionPolicy %s)", assemblyInstance.Name, policy)
+	}
+	sync.logger.Info("Adopting existing Assembly from LM", LogKeys.AssemblyID, assemblyInstance.ID)
+	sync.recordNormalEvent(EventReasons.Adopted, "Adopted existing Assembly %s from LM (adoptionPolicy %s)", assemblyInstance.ID, policy)
+	return ""
+}
+
+// onAdoptionConflict leaves the status clear of the Assembly owned by someone else and reports the conflict
+func (sync *AssemblySynchronizer) onAdoptionConflict(conflict string) {
+	k8sInstance := sync.k8sInstance
+	sync.logger.Info("Assembly in LM will not be adopted", "reason", conflict)
+	k8sInstance.Status.State = "None"
+	k8sInstance.Status.DescriptorName = ""
+	k8sInstance.Status.Properties = make(map[string]string)
+	if degraded := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded); degraded == nil || degraded.Reason != ConditionReasons.AdoptionConflict {
+		sync.recordWarningEvent(EventReasons.AdoptionConflict, "%s", conflict)
+	}
+	setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionTrue, ConditionReasons.AdoptionConflict, conflict)
+	sync.adoptionConflict = conflict
+}
@@ -0,0 +1,211 @@
+package assembly
+
+import (
+	"strings"
+	"testing"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func setDeletionPolicyAnnotation(policy string) func(instance *stratossv1alpha1.Assembly) {
+	return func(instance *stratossv1alpha1.Assembly) {
+		instance.SetAnnotations(map[string]string{stratossv1alpha1.DeletionPolicyAnnotation: policy})
+	}
+}
+
+func TestReconcileOrphansAssembly(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		annotation string
+	}{
+		{name: "spec", spec: stratossv1alpha1.DeletionPolicies.Orphan},
+		{name: "annotation overrides spec", spec: stratossv1alpha1.DeletionPolicies.Delete, annotation: stratossv1alpha1.DeletionPolicies.Orphan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := activeSpec()
+			spec.DeletionPolicy = tt.spec
+			env := newTestEnv(t, newTestAssembly("a1", spec))
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", isActive)
+			if tt.annotation != "" {
+				env.edit("a1", setDeletionPolicyAnnotation(tt.annotation))
+			}
+			env.events()
+
+			env.markDeleted("a1")
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+			})
+			if requests := env.lm.IntentRequests("deleteAssembly"); len(requests) != 0 {
+				t.Errorf("Expected the Assembly to be left in LM but found %d deleteAssembly requests", len(requests))
+			}
+			if _, found := env.lm.GetAssembly("a1"); !found {
+				t.Errorf("Expected the Assembly to still exist in LM")
+			}
+			if events := env.events(); !hasEvent(events, EventReasons.Orphaned) {
+				t.Errorf("Expected an %s event but found %v", EventReasons.Orphaned, events)
+			}
+		})
+	}
+}
+
+func TestReconcileBlocksDeletionWithInvalidPolicyAnnotation(t *testing.T) {
+	env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+	env.edit("a1", setDeletionPolicyAnnotation("Keep"))
+
+	env.markDeleted("a1")
+	for i := 0; i < 3; i++ {
+		if _, err := env.reconcile("a1"); err == nil {
+			t.Fatalf("Expected the reconcile to report the invalid deletion policy")
+		}
+	}
+	instance := env.get("a1")
+	if !finalizerContains(instance.GetFinalizers(), assemblyFinalizer) {
+		t.Errorf("Expected the finalizer to be kept whilst the deletion policy is invalid")
+	}
+	if requests := env.lm.IntentRequests("deleteAssembly"); len(requests) != 0 {
+		t.Errorf("Expected the Assembly not to be deleted with an invalid deletion policy but found %d deleteAssembly requests", len(requests))
+	}
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Synced); status != corev1.ConditionFalse || reason != ConditionReasons.SyncError {
+		t.Errorf("Expected Synced to be False (%s) but was %s (%s)", ConditionReasons.SyncError, status, reason)
+	}
+}
+
+// addExistingAssembly adds an Assembly to LM which was not created by the operator
+func (env *testEnv) addExistingAssembly(name string, descriptorName string) string {
+	return env.lm.AddAssembly(lm.Assembly{
+		Name:           name,
+		State:          stratossv1alpha1.AssemblyStates.Active,
+		DescriptorName: descriptorName,
+		Properties:     []lm.AssemblyProperty{{Name: "size", Value: "small"}},
+	})
+}
+
+func countEvents(events []string, reason string) int {
+	count := 0
+	for _, event := range events {
+		if strings.Contains(event, " "+reason+" ") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestReconcileLeavesAssemblyNotAdopted(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		descriptorName string
+	}{
+		{name: "Never", policy: stratossv1alpha1.AdoptionPolicies.Never, descriptorName: "assembly::test::1.0"},
+		{name: "IfMatching with another descriptor", policy: stratossv1alpha1.AdoptionPolicies.IfMatching, descriptorName: "assembly::other::1.0"},
+		{name: "default with another descriptor", descriptorName: "assembly::other::1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := activeSpec()
+			spec.AdoptionPolicy = tt.policy
+			env := newTestEnv(t, newTestAssembly("a1", spec))
+			defer env.close()
+			env.completeImmediately()
+			env.addExistingAssembly("a1", tt.descriptorName)
+
+			for i := 0; i < 3; i++ {
+				env.mustReconcile("a1")
+			}
+			if requests := env.intentRequests(); requests != 0 {
+				t.Errorf("Expected the Assembly to be left untouched but found %d intent requests", requests)
+			}
+			instance := env.get("a1")
+			if instance.Status.ID != "" {
+				t.Errorf("Expected the Assembly not to be adopted but status ID was %s", instance.Status.ID)
+			}
+			if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Degraded); status != corev1.ConditionTrue || reason != ConditionReasons.AdoptionConflict {
+				t.Errorf("Expected Degraded to be True (%s) but was %s (%s)", ConditionReasons.AdoptionConflict, status, reason)
+			}
+			events := env.events()
+			if count := countEvents(events, EventReasons.AdoptionConflict); count != 1 {
+				t.Errorf("Expected a single %s event but found %v", EventReasons.AdoptionConflict, events)
+			}
+			if hasEvent(events, EventReasons.Adopted) {
+				t.Errorf("Expected no %s event but found %v", EventReasons.Adopted, events)
+			}
+
+			// The Assembly in LM is not ours to delete
+			env.markDeleted("a1")
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				return !finalizerContains(instance.GetFinalizers(), assemblyFinalizer)
+			})
+			if requests := env.lm.IntentRequests("deleteAssembly"); len(requests) != 0 {
+				t.Errorf("Expected the Assembly to be left in LM but found %d deleteAssembly requests", len(requests))
+			}
+			if _, found := env.lm.GetAssembly("a1"); !found {
+				t.Errorf("Expected the Assembly to still exist in LM")
+			}
+		})
+	}
+}
+
+func TestReconcileAdoptsAssembly(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		descriptorName string
+	}{
+		{name: "IfMatching with the same descriptor", policy: stratossv1alpha1.AdoptionPolicies.IfMatching, descriptorName: "assembly::test::1.0"},
+		{name: "Always", policy: stratossv1alpha1.AdoptionPolicies.Always, descriptorName: "assembly::other::1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := activeSpec()
+			spec.AdoptionPolicy = tt.policy
+			env := newTestEnv(t, newTestAssembly("a1", spec))
+			defer env.close()
+			env.completeImmediately()
+			id := env.addExistingAssembly("a1", tt.descriptorName)
+
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				return instance.Status.DescriptorName == spec.DescriptorName && isActive(instance)
+			})
+			if requests := env.lm.IntentRequests("createAssembly"); len(requests) != 0 {
+				t.Errorf("Expected the existing Assembly to be used but found %d createAssembly requests", len(requests))
+			}
+			instance := env.get("a1")
+			if instance.Status.ID != id {
+				t.Errorf("Expected the Assembly %s to be adopted but status ID was %s", id, instance.Status.ID)
+			}
+			if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Degraded); status == corev1.ConditionTrue {
+				t.Errorf("Expected Degraded not to be True but was %s (%s)", status, reason)
+			}
+			if events := env.events(); countEvents(events, EventReasons.Adopted) != 1 {
+				t.Errorf("Expected a single %s event but found %v", EventReasons.Adopted, events)
+			}
+		})
+	}
+}
+
+func TestReconcileNeverFlagsAssemblyItCreated(t *testing.T) {
+	spec := activeSpec()
+	spec.AdoptionPolicy = stratossv1alpha1.AdoptionPolicies.Never
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	defer env.close()
+	env.lm.SetProcessLifecycle(fake.CreateAssemblyIntent, fake.ProcessLifecycle{Steps: []string{"In Progress"}})
+
+	env.reconcileUntil("a1", isActive)
+	instance := env.get("a1")
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Degraded); status == corev1.ConditionTrue {
+		t.Errorf("Expected Degraded not to be True but was %s (%s)", status, reason)
+	}
+	if events := env.events(); hasEvent(events, EventReasons.AdoptionConflict) || hasEvent(events, EventReasons.Adopted) {
+		t.Errorf("Expected no adoption events for an Assembly created by the operator but found %v", events)
+	}
+}
@@ -0,0 +1,132 @@
+package assembly
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// pollSettings controls how often LM is polled for an Assembly
+type pollSettings struct {
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	jitter          float64
+	resyncPeriod    time.Duration
+}
+
+func (settings pollSettings) validate() error {
+	if settings.initialInterval <= 0 {
+		return fmt.Errorf("Process poll initial interval must be greater than 0")
+	}
+	if settings.maxInterval < settings.initialInterval {
+		return fmt.Errorf("Process poll max interval must not be less than the initial interval")
+	}
+	if settings.jitter < 0 || settings.jitter > 1 {
+		return fmt.Errorf("Process poll jitter must be between 0 and 1")
+	}
+	if settings.resyncPeriod < 0 {
+		return fmt.Errorf("Resync period must not be negative")
+	}
+	return nil
+}
+
+// forAssembly applies the polling policy of an Assembly over the operator defaults
+func (settings pollSettings) forAssembly(instance *stratossv1alpha1.Assembly) pollSettings {
+	policy := instance.Spec.Polling
+	if policy == nil {
+		return settings
+	}
+	if policy.InitialInterval != nil && policy.InitialInterval.Duration > 0 {
+		settings.initialInterval = policy.InitialInterval.Duration
+	}
+	if policy.MaxInterval != nil && policy.MaxInterval.Duration > 0 {
+		settings.maxInterval = policy.MaxInterval.Duration
+	}
+	if settings.maxInterval < settings.initialInterval {
+		settings.maxInterval = settings.initialInterval
+	}
+	if policy.ResyncPeriod != nil && policy.ResyncPeriod.Duration >= 0 {
+		settings.resyncPeriod = policy.ResyncPeriod.Duration
+	}
+	return settings
+}
+
+func (settings pollSettings) withJitter(delay time.Duration) time.Duration {
+	if settings.jitter <= 0 {
+		return delay
+	}
+	return time.Duration(float64(delay) * (1 + settings.jitter*(2*rand.Float64()-1)))
+}
+
+// pollState is the number of times a process has been checked so far
+type pollState struct {
+	processID string
+	polls     int
+}
+
+// processPoller tracks how many times the process in progress on each Assembly has been checked, so the delay between checks
+// can grow the longer a process runs. Shared by all reconciles, so safe for concurrent use
+type processPoller struct {
+	settings pollSettings
+	mu       sync.Mutex
+	states   map[types.NamespacedName]*pollState
+}
+
+func newProcessPoller(settings pollSettings) *processPoller {
+	return &processPoller{
+		settings: settings,
+		states:   make(map[types.NamespacedName]*pollState),
+	}
+}
+
+// nextPoll returns the delay before the next check on a process, starting from the initial interval for a process not seen before
+func (poller *processPoller) nextPoll(instance *stratossv1alpha1.Assembly, processID string) time.Duration {
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	poller.mu.Lock()
+	state, ok := poller.states[key]
+	if !ok || state.processID != processID {
+		state = &pollState{processID: processID}
+		poller.states[key] = state
+	}
+	polls := state.polls
+	state.polls++
+	poller.mu.Unlock()
+
+	settings := poller.settings.forAssembly(instance)
+	delay := float64(settings.initialInterval) * math.Pow(2, float64(polls))
+	if delay > float64(settings.maxInterval) {
+		delay = float64(settings.maxInterval)
+	}
+	return settings.withJitter(time.Duration(delay))
+}
+
+// resync returns the delay before the Assembly is checked for changes made outside of the operator, or 0 if it should not be
+func (poller *processPoller) resync(instance *stratossv1alpha1.Assembly) time.Duration {
+	settings := poller.settings.forAssembly(instance)
+	if settings.resyncPeriod <= 0 {
+		return 0
+	}
+	return settings.withJitter(settings.resyncPeriod)
+}
+
+// resyncInterval returns the shortest time, allowing for jitter, after which the Assembly may be resynced. Not enabled when resyncs are disabled
+func (poller *processPoller) resyncInterval(instance *stratossv1alpha1.Assembly) (interval time.Duration, enabled bool) {
+	settings := poller.settings.forAssembly(instance)
+	if settings.resyncPeriod <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(settings.resyncPeriod) * (1 - settings.jitter)), true
+}
+
+// forget clears the state of an Assembly with no process in progress
+func (poller *processPoller) forget(instance *stratossv1alpha1.Assembly) {
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	poller.mu.Lock()
+	delete(poller.states, key)
+	poller.mu.Unlock()
+}
@@ -0,0 +1,136 @@
+package assembly
+
+import (
+	"testing"
+	"time"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var exactPollSettings = pollSettings{
+	initialInterval: time.Second,
+	maxInterval:     8 * time.Second,
+	resyncPeriod:    10 * time.Minute,
+}
+
+func withPollingPolicy(policy *stratossv1alpha1.PollingPolicy) *stratossv1alpha1.Assembly {
+	spec := activeSpec()
+	spec.Polling = policy
+	return newTestAssembly("a1", spec)
+}
+
+func durationPtr(duration time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: duration}
+}
+
+func TestPollSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  pollSettings
+		expectErr bool
+	}{
+		{name: "valid", settings: testPollSettings},
+		{name: "resync disabled", settings: pollSettings{initialInterval: time.Second, maxInterval: time.Second}},
+		{name: "no initial interval", settings: pollSettings{maxInterval: time.Second}, expectErr: true},
+		{name: "max interval less than initial interval", settings: pollSettings{initialInterval: 2 * time.Second, maxInterval: time.Second}, expectErr: true},
+		{name: "negative jitter", settings: pollSettings{initialInterval: time.Second, maxInterval: time.Second, jitter: -0.1}, expectErr: true},
+		{name: "jitter above 1", settings: pollSettings{initialInterval: time.Second, maxInterval: time.Second, jitter: 1.5}, expectErr: true},
+		{name: "negative resync period", settings: pollSettings{initialInterval: time.Second, maxInterval: time.Second, resyncPeriod: -time.Second}, expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.validate()
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected %+v to be rejected", tt.settings)
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("Expected %+v to be accepted: %s", tt.settings, err)
+			}
+		})
+	}
+}
+
+func TestNextPollBacksOff(t *testing.T) {
+	poller := newProcessPoller(exactPollSettings)
+	a1 := newTestAssembly("a1", activeSpec())
+
+	for _, expected := range []time.Duration{1, 2, 4, 8, 8} {
+		if delay := poller.nextPoll(a1, "p1"); delay != expected*time.Second {
+			t.Errorf("Expected a delay of %s but was %s", expected*time.Second, delay)
+		}
+	}
+	if delay := poller.nextPoll(newTestAssembly("a2", activeSpec()), "p1"); delay != time.Second {
+		t.Errorf("Expected the polling of another Assembly to start from the initial interval but was %s", delay)
+	}
+	if delay := poller.nextPoll(a1, "p2"); delay != time.Second {
+		t.Errorf("Expected the polling of a new process to start from the initial interval but was %s", delay)
+	}
+	poller.nextPoll(a1, "p2")
+	poller.forget(a1)
+	if delay := poller.nextPoll(a1, "p2"); delay != time.Second {
+		t.Errorf("Expected the polling to start from the initial interval once forgotten but was %s", delay)
+	}
+}
+
+func TestNextPollWithJitter(t *testing.T) {
+	settings := exactPollSettings
+	settings.jitter = 0.2
+	poller := newProcessPoller(settings)
+	for i := 0; i < 100; i++ {
+		if delay := poller.nextPoll(newTestAssembly("a1", activeSpec()), "p1"); delay < 800*time.Millisecond || delay > 1200*time.Millisecond {
+			t.Fatalf("Expected a delay within 20%% of 1s but was %s", delay)
+		}
+		poller.forget(newTestAssembly("a1", activeSpec()))
+	}
+}
+
+func TestNextPollWithPollingPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *stratossv1alpha1.PollingPolicy
+		expect []time.Duration
+	}{
+		{name: "operator defaults", expect: []time.Duration{1, 2, 4, 8, 8}},
+		{name: "initial interval", policy: &stratossv1alpha1.PollingPolicy{InitialInterval: durationPtr(2 * time.Second)}, expect: []time.Duration{2, 4, 8, 8}},
+		{name: "max interval", policy: &stratossv1alpha1.PollingPolicy{MaxInterval: durationPtr(3 * time.Second)}, expect: []time.Duration{1, 2, 3, 3}},
+		{name: "initial interval above operator max interval", policy: &stratossv1alpha1.PollingPolicy{InitialInterval: durationPtr(10 * time.Second)}, expect: []time.Duration{10, 10}},
+		{name: "zero intervals use operator defaults", policy: &stratossv1alpha1.PollingPolicy{InitialInterval: durationPtr(0), MaxInterval: durationPtr(0)}, expect: []time.Duration{1, 2, 4, 8, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poller := newProcessPoller(exactPollSettings)
+			instance := withPollingPolicy(tt.policy)
+			for _, expected := range tt.expect {
+				if delay := poller.nextPoll(instance, "p1"); delay != expected*time.Second {
+					t.Errorf("Expected a delay of %s but was %s", expected*time.Second, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestResyncWithPollingPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        *stratossv1alpha1.PollingPolicy
+		expectResync  time.Duration
+		expectEnabled bool
+	}{
+		{name: "operator default", expectResync: 10 * time.Minute, expectEnabled: true},
+		{name: "resync period", policy: &stratossv1alpha1.PollingPolicy{ResyncPeriod: durationPtr(time.Minute)}, expectResync: time.Minute, expectEnabled: true},
+		{name: "resync disabled", policy: &stratossv1alpha1.PollingPolicy{ResyncPeriod: durationPtr(0)}},
+		{name: "negative resync period uses operator default", policy: &stratossv1alpha1.PollingPolicy{ResyncPeriod: durationPtr(-time.Minute)}, expectResync: 10 * time.Minute, expectEnabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poller := newProcessPoller(exactPollSettings)
+			instance := withPollingPolicy(tt.policy)
+			if resync := poller.resync(instance); resync != tt.expectResync {
+				t.Errorf("Expected a resync after %s but was %s", tt.expectResync, resync)
+			}
+			if interval, enabled := poller.resyncInterval(instance); enabled != tt.expectEnabled || interval != tt.expectResync {
+				t.Errorf("Expected a resync interval of %s (enabled %t) but was %s (enabled %t)", tt.expectResync, tt.expectEnabled, interval, enabled)
+			}
+		})
+	}
+}
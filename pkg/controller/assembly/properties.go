@@ -0,0 +1,171 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// redactedValue replaces the value of properties read from Secrets wherever they would otherwise be shown (status, logs)
+const redactedValue = "<redacted>"
+
+// secretPropertyNames returns the names of properties with values read from Secrets
+func secretPropertyNames(spec stratossv1alpha1.AssemblySpec) map[string]bool {
+	names := make(map[string]bool)
+	for _, source := range spec.PropertiesFrom {
+		if source.ValueFrom.SecretKeyRef != nil {
+			names[source.Name] = true
+		}
+	}
+	return names
+}
+
+// redactProperty returns the value of a property safe to be shown in status or logs
+func (sync *AssemblySynchronizer) redactProperty(name string, value string) string {
+	if sync.secretProperties == nil {
+		sync.secretProperties = secretPropertyNames(sync.k8sInstance.Spec)
+	}
+	if sync.secretProperties[name] {
+		return redactedValue
+	}
+	return value
+}
+
+func optional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// resolveProperties builds the properties to be sent to LM from the properties on the spec and the values of any referenced Secret or ConfigMap keys
+func (sync *AssemblySynchronizer) resolveProperties() (stopSync bool) {
+	if sync.k8sInstance.GetDeletionTimestamp() != nil {
+		// Properties are not needed to delete the Assembly, so a missing Secret or ConfigMap must not block it
+		return false
+	}
+	properties, err := sync.resolvePropertyValues()
+	if err != nil {
+		return sync.onPropertiesError(err)
+	}
+	sync.desiredProperties = properties
+	return false
+}
+
+// resolvePropertyValues reads the value of each property, recording the versions of the Secrets and ConfigMaps read
+func (sync *AssemblySynchronizer) resolvePropertyValues() (map[string]string, error) {
+	k8sInstance := sync.k8sInstance
+	properties := make(map[string]string)
+	sync.sourceVersions = nil
+	for name, value := range k8sInstance.Spec.Properties {
+		properties[name] = value
+	}
+	for _, source := range k8sInstance.Spec.PropertiesFrom {
+		value, found, err := sync.resolvePropertySource(source)
+		if err != nil {
+			sync.logger.Error(err, "Failed to resolve Assembly property", LogKeys.PropertyName, source.Name)
+			return nil, err
+		}
+		if found {
+			properties[source.Name] = value
+		}
+	}
+	return properties, nil
+}
+
+func (sync *AssemblySynchronizer) resolvePropertySource(source stratossv1alpha1.PropertySource) (value string, found bool, err error) {
+	namespace := sync.k8sInstance.Namespace
+	secretKeyRef := source.ValueFrom.SecretKeyRef
+	configMapKeyRef := source.ValueFrom.ConfigMapKeyRef
+	if (secretKeyRef == nil) == (configMapKeyRef == nil) {
+		return "", false, fmt.Errorf("Property %s must set exactly one of secretKeyRef or configMapKeyRef", source.Name)
+	}
+	if secretKeyRef != nil {
+		secret := &corev1.Secret{}
+		if err := sync.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: secretKeyRef.Name}, secret); err != nil {
+			if errors.IsNotFound(err) && optional(secretKeyRef.Optional) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("Unable to read Secret %s for property %s: %s", secretKeyRef.Name, source.Name, err)
+		}
+		sync.sourceVersions = append(sync.sourceVersions, fmt.Sprintf("Secret/%s@%s", secret.Name, secret.ResourceVersion))
+		data, ok := secret.Data[secretKeyRef.Key]
+		if !ok {
+			if optional(secretKeyRef.Optional) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("Secret %s has no key %s for property %s", secretKeyRef.Name, secretKeyRef.Key, source.Name)
+		}
+		return string(data), true, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := sync.k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: configMapKeyRef.Name}, configMap); err != nil {
+		if errors.IsNotFound(err) && optional(configMapKeyRef.Optional) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("Unable to read ConfigMap %s for property %s: %s", configMapKeyRef.Name, source.Name, err)
+	}
+	sync.sourceVersions = append(sync.sourceVersions, fmt.Sprintf("ConfigMap/%s@%s", configMap.Name, configMap.ResourceVersion))
+	if data, ok := configMap.Data[configMapKeyRef.Key]; ok {
+		return data, true, nil
+	}
+	if data, ok := configMap.BinaryData[configMapKeyRef.Key]; ok {
+		return string(data), true, nil
+	}
+	if optional(configMapKeyRef.Optional) {
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("ConfigMap %s has no key %s for property %s", configMapKeyRef.Name, configMapKeyRef.Key, source.Name)
+}
+
+func (sync *AssemblySynchronizer) onPropertiesError(err error) (stopSync bool) {
+	sync.errors = append(sync.errors, err)
+	sync.stopSync = true
+	sync.requeue = true
+	return sync.stopSync
+}
+
+// referencesSecret returns true if any property of the Assembly is read from the named Secret
+func referencesSecret(instance *stratossv1alpha1.Assembly, name string) bool {
+	for _, source := range instance.Spec.PropertiesFrom {
+		if source.ValueFrom.SecretKeyRef != nil && source.ValueFrom.SecretKeyRef.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesConfigMap returns true if any property, or the maintenance windows, of the Assembly are read from the named ConfigMap
+func referencesConfigMap(instance *stratossv1alpha1.Assembly, name string) bool {
+	if referencesMaintenanceWindows(instance, name) {
+		return true
+	}
+	for _, source := range instance.Spec.PropertiesFrom {
+		if source.ValueFrom.ConfigMapKeyRef != nil && source.ValueFrom.ConfigMapKeyRef.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// referencingAssemblies maps a change to a Secret or ConfigMap to reconcile requests for each Assembly, in the same namespace, with properties read from it
+func referencingAssemblies(k8sClient client.Client, references func(instance *stratossv1alpha1.Assembly, name string) bool) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		assemblies := &stratossv1alpha1.AssemblyList{}
+		if err := k8sClient.List(context.TODO(), assemblies, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+			log.Error(err, "Failed to list Assemblies referencing changed object", "Namespace", obj.Meta.GetNamespace(), "Name", obj.Meta.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for i := range assemblies.Items {
+			if references(&assemblies.Items[i], obj.Meta.GetName()) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: assemblies.Items[i].Namespace, Name: assemblies.Items[i].Name}})
+			}
+		}
+		return requests
+	}
+}
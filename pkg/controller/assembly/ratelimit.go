@@ -0,0 +1,67 @@
+package assembly
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// rateLimitSettings controls how soon a reconcile which failed, or asked to be requeued without a delay, is run again
+type rateLimitSettings struct {
+	// Delay before the first requeue of an Assembly, doubled on each consecutive failure
+	baseDelay time.Duration
+	// Upper limit on the delay before an Assembly is requeued
+	maxDelay time.Duration
+	// Rate of requeues allowed across all Assemblies, and the burst allowed above it
+	qps   float64
+	burst int
+}
+
+func (settings rateLimitSettings) validate() error {
+	if settings.baseDelay <= 0 {
+		return fmt.Errorf("Rate limit base delay must be greater than 0")
+	}
+	if settings.maxDelay < settings.baseDelay {
+		return fmt.Errorf("Rate limit max delay must not be less than the base delay")
+	}
+	if settings.qps <= 0 {
+		return fmt.Errorf("Rate limit QPS must be greater than 0")
+	}
+	if settings.burst <= 0 {
+		return fmt.Errorf("Rate limit burst must be greater than 0")
+	}
+	return nil
+}
+
+// newRateLimiter returns a rate limiter with per-Assembly exponential backoff, bounded overall by a token bucket. Safe for concurrent use
+func (settings rateLimitSettings) newRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(settings.baseDelay, settings.maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(settings.qps), settings.burst)},
+	)
+}
+
+// useRateLimiter makes the work queue of the controller apply the rate limiter to reconciles which fail or ask to be requeued without a delay,
+// in place of its default rate limiter. This version of controller-runtime has no option for it, so the function the controller makes its
+// queue with when it starts is replaced. Errors are still returned to the controller, so it logs them and counts them in its metrics
+func useRateLimiter(c controller.Controller, name string, limiter workqueue.RateLimiter) error {
+	value := reflect.ValueOf(c)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	var makeQueue reflect.Value
+	if value.Kind() == reflect.Struct {
+		makeQueue = value.FieldByName("MakeQueue")
+	}
+	if !makeQueue.IsValid() || !makeQueue.CanSet() || makeQueue.Type() != reflect.TypeOf(func() workqueue.RateLimitingInterface { return nil }) {
+		return fmt.Errorf("Unable to set the rate limiter of controller %s, as the work queue it makes cannot be replaced", name)
+	}
+	makeQueue.Set(reflect.ValueOf(func() workqueue.RateLimitingInterface {
+		return workqueue.NewNamedRateLimitingQueue(limiter, name)
+	}))
+	return nil
+}
@@ -0,0 +1,165 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func testRequest(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+}
+
+func TestRateLimiterBacksOffPerAssembly(t *testing.T) {
+	settings := rateLimitSettings{baseDelay: 10 * time.Millisecond, maxDelay: 80 * time.Millisecond, qps: 1000, burst: 1000}
+	limiter := settings.newRateLimiter()
+	a1, a2 := testRequest("a1"), testRequest("a2")
+
+	for _, expected := range []time.Duration{10, 20, 40, 80, 80} {
+		if delay := limiter.When(a1); delay != expected*time.Millisecond {
+			t.Errorf("Expected a delay of %s but was %s", expected*time.Millisecond, delay)
+		}
+	}
+	if failures := limiter.NumRequeues(a1); failures != 5 {
+		t.Errorf("Expected 5 requeues to be counted but was %d", failures)
+	}
+	if delay := limiter.When(a2); delay != settings.baseDelay {
+		t.Errorf("Expected the backoff of another Assembly to start from the base delay but was %s", delay)
+	}
+
+	limiter.Forget(a1)
+	if failures := limiter.NumRequeues(a1); failures != 0 {
+		t.Errorf("Expected the requeues to be forgotten but %d were counted", failures)
+	}
+	if delay := limiter.When(a1); delay != settings.baseDelay {
+		t.Errorf("Expected the backoff to start again from the base delay once forgotten but was %s", delay)
+	}
+}
+
+func TestRateLimiterLimitsOverallRate(t *testing.T) {
+	settings := rateLimitSettings{baseDelay: time.Millisecond, maxDelay: time.Second, qps: 1, burst: 2}
+	limiter := settings.newRateLimiter()
+
+	for _, name := range []string{"a1", "a2"} {
+		if delay := limiter.When(testRequest(name)); delay != settings.baseDelay {
+			t.Errorf("Expected requeues within the burst to only be delayed by the backoff but %s was delayed by %s", name, delay)
+		}
+	}
+	if delay := limiter.When(testRequest("a3")); delay < 900*time.Millisecond {
+		t.Errorf("Expected a requeue above the burst to wait for the overall rate but was delayed by %s", delay)
+	}
+}
+
+// queueController has the same work queue factory as the controller made by controller-runtime
+type queueController struct {
+	controller.Controller
+	MakeQueue func() workqueue.RateLimitingInterface
+}
+
+func TestUseRateLimiter(t *testing.T) {
+	settings := rateLimitSettings{baseDelay: time.Hour, maxDelay: time.Hour, qps: 1000, burst: 1000}
+	limiter := settings.newRateLimiter()
+	c := &queueController{}
+	if err := useRateLimiter(c, "test", limiter); err != nil {
+		t.Fatalf("Failed to set rate limiter: %s", err)
+	}
+
+	queue := c.MakeQueue()
+	defer queue.ShutDown()
+	queue.AddRateLimited(testRequest("a1"))
+	if failures := limiter.NumRequeues(testRequest("a1")); failures != 1 {
+		t.Errorf("Expected the work queue to use the rate limiter but it counted %d requeues", failures)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("Expected the requeue to wait for the delay of the rate limiter")
+	}
+}
+
+func TestUseRateLimiterFailsWithoutQueueFactory(t *testing.T) {
+	var c struct{ controller.Controller }
+	if err := useRateLimiter(&c, "test", rateLimitSettings{baseDelay: time.Second, maxDelay: time.Second, qps: 1, burst: 1}.newRateLimiter()); err == nil {
+		t.Errorf("Expected a controller without a work queue factory to be reported")
+	}
+}
+
+// TestConcurrentReconciles reconciles several Assemblies at once with workers taking them from a rate limited work queue,
+// as the controller does, so running it with -race checks the reconciler and the LM client are safe for concurrent use
+func TestConcurrentReconciles(t *testing.T) {
+	const assemblies = 8
+	const workers = 4
+	var objs []runtime.Object
+	for i := 0; i < assemblies; i++ {
+		objs = append(objs, newTestAssembly(fmt.Sprintf("a%d", i), activeSpec()))
+	}
+	env := newTestEnv(t, objs...)
+	defer env.close()
+	env.completeImmediately()
+
+	settings := rateLimitSettings{baseDelay: time.Millisecond, maxDelay: 10 * time.Millisecond, qps: 1000, burst: 100}
+	queue := workqueue.NewNamedRateLimitingQueue(settings.newRateLimiter(), "test")
+	for i := 0; i < assemblies; i++ {
+		queue.Add(testRequest(fmt.Sprintf("a%d", i)))
+	}
+	var mu sync.Mutex
+	active := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, shutdown := queue.Get()
+				if shutdown {
+					return
+				}
+				request := item.(reconcile.Request)
+				result, err := env.reconciler.Reconcile(request)
+				instance := &stratossv1alpha1.Assembly{}
+				getErr := env.k8sClient.Get(context.TODO(), request.NamespacedName, instance)
+				switch {
+				case err != nil:
+					queue.AddRateLimited(request)
+				case getErr == nil && isActive(instance):
+					queue.Forget(request)
+					mu.Lock()
+					active[request.Name] = true
+					if len(active) == assemblies {
+						queue.ShutDown()
+					}
+					mu.Unlock()
+				case result.Requeue || result.RequeueAfter > 0:
+					// Polled sooner than the process poller would, to keep the test short
+					queue.Forget(request)
+					queue.AddAfter(request, time.Millisecond)
+				default:
+					queue.Add(request)
+				}
+				queue.Done(request)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		queue.ShutDown()
+		t.Fatalf("Timed out waiting for the Assemblies to become Active")
+	}
+	if requests := env.lm.IntentRequests("createAssembly"); len(requests) != assemblies {
+		t.Errorf("Expected each Assembly to be created once but found %d createAssembly requests", len(requests))
+	}
+}
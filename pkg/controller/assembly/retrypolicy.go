@@ -0,0 +1,170 @@
+package assembly
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultMaxRetries          = 3
+	defaultRetryInitialDelay   = 30 * time.Second
+	defaultRetryMaxDelay       = 10 * time.Minute
+	retryAnnotationDescription = "the stratoss.accantosystems.com/retry annotation is changed"
+)
+
+// retrySettings is a RetryPolicy with defaults applied
+type retrySettings struct {
+	stopAfterFailure bool
+	maxRetries       int32
+	initialDelay     time.Duration
+	maxDelay         time.Duration
+}
+
+func retrySettingsFor(spec stratossv1alpha1.AssemblySpec) retrySettings {
+	settings := retrySettings{
+		maxRetries:   defaultMaxRetries,
+		initialDelay: defaultRetryInitialDelay,
+		maxDelay:     defaultRetryMaxDelay,
+	}
+	policy := spec.RetryPolicy
+	if policy == nil {
+		return settings
+	}
+	settings.stopAfterFailure = policy.Mode == stratossv1alpha1.RetryModes.StopAfterFailure
+	if policy.MaxRetries != nil {
+		settings.maxRetries = *policy.MaxRetries
+	}
+	if policy.Backoff.InitialDelay != nil {
+		settings.initialDelay = policy.Backoff.InitialDelay.Duration
+	}
+	if policy.Backoff.MaxDelay != nil {
+		settings.maxDelay = policy.Backoff.MaxDelay.Duration
+	}
+	return settings
+}
+
+// delay returns how long to wait before requesting an intent again after the given number of consecutive failures
+func (settings retrySettings) delay(failures int32) time.Duration {
+	delay := float64(settings.initialDelay) * math.Pow(2, float64(failures-1))
+	if delay > float64(settings.maxDelay) {
+		return settings.maxDelay
+	}
+	return time.Duration(delay)
+}
+
+func findFailedIntent(instance *stratossv1alpha1.Assembly, intentType string) *stratossv1alpha1.FailedIntent {
+	for i := range instance.Status.FailedIntents {
+		if instance.Status.FailedIntents[i].IntentType == intentType {
+			return &instance.Status.FailedIntents[i]
+		}
+	}
+	return nil
+}
+
+func removeFailedIntent(instance *stratossv1alpha1.Assembly, intentType string) {
+	failedIntents := instance.Status.FailedIntents[:0]
+	for _, failedIntent := range instance.Status.FailedIntents {
+		if failedIntent.IntentType != intentType {
+			failedIntents = append(failedIntents, failedIntent)
+		}
+	}
+	instance.Status.FailedIntents = failedIntents
+}
+
+// clearFailedIntents forgets failed intents recorded against an earlier desired state or retry annotation, so they are requested again.
+// Changes to the spec which do not change the Assembly in LM, such as to the polling settings or suspending it, leave failures in place.
+// Must be called once properties have been resolved, as the desired state includes the versions of referenced Secrets and ConfigMaps
+func (sync *AssemblySynchronizer) clearFailedIntents() {
+	k8sInstance := sync.k8sInstance
+	if len(k8sInstance.Status.FailedIntents) == 0 {
+		return
+	}
+	currentHash := sync.desiredStateHash()
+	currentAnnotation := k8sInstance.GetAnnotations()[stratossv1alpha1.RetryAnnotation]
+	var failedIntents []stratossv1alpha1.FailedIntent
+	changed := false
+	for _, failedIntent := range k8sInstance.Status.FailedIntents {
+		if failedIntent.SpecHash == "" {
+			// Failed before the desired state was recorded on the process, so attributed to the current one
+			failedIntent.SpecHash = currentHash
+			changed = true
+		}
+		if failedIntent.SpecHash != currentHash || failedIntent.RetryAnnotation != currentAnnotation {
+			sync.logger.Info("Desired state or retry annotation changed since intent failed, clearing failure", "intentType", failedIntent.IntentType)
+			changed = true
+			continue
+		}
+		failedIntents = append(failedIntents, failedIntent)
+	}
+	if changed {
+		k8sInstance.Status.FailedIntents = failedIntents
+		sync.needsStatusUpdate = true
+	}
+}
+
+// onIntentOutcome records the failure of the intent of the last process, or clears previous failures when it completes
+func (sync *AssemblySynchronizer) onIntentOutcome() {
+	k8sInstance := sync.k8sInstance
+	lastProcess := k8sInstance.Status.LastProcess
+	switch lastProcess.Status {
+	case stratossv1alpha1.ProcessStatus.Completed:
+		removeFailedIntent(k8sInstance, lastProcess.IntentType)
+	case stratossv1alpha1.ProcessStatus.Failed:
+		failedIntent := findFailedIntent(k8sInstance, lastProcess.IntentType)
+		if failedIntent == nil {
+			k8sInstance.Status.FailedIntents = append(k8sInstance.Status.FailedIntents, stratossv1alpha1.FailedIntent{IntentType: lastProcess.IntentType})
+			failedIntent = &k8sInstance.Status.FailedIntents[len(k8sInstance.Status.FailedIntents)-1]
+		}
+		failedIntent.Failures++
+		// The spec may have changed whilst the process was running, in which case the new desired state is not held back
+		failedIntent.SpecHash = lastProcess.DesiredStateHash
+		failedIntent.RetryAnnotation = k8sInstance.GetAnnotations()[stratossv1alpha1.RetryAnnotation]
+		settings := retrySettingsFor(k8sInstance.Spec)
+		if settings.stopAfterFailure || failedIntent.Failures > settings.maxRetries {
+			failedIntent.Terminal = true
+			failedIntent.NextRetryTime = nil
+			sync.recordWarningEvent(EventReasons.RetriesExhausted, "%s failed %d time(s), it will not be requested again until the spec changes or %s", failedIntent.IntentType, failedIntent.Failures, retryAnnotationDescription)
+		} else {
+			// Status times only hold whole seconds, round up so the retry is never early
+			nextRetryTime := metav1.NewTime(time.Now().Add(settings.delay(failedIntent.Failures) + time.Second).Truncate(time.Second))
+			failedIntent.NextRetryTime = &nextRetryTime
+		}
+	}
+}
+
+// checkRetryPolicy holds back an intent which has failed, until its next retry time or indefinitely when terminal
+func (sync *AssemblySynchronizer) checkRetryPolicy(intentType string) (stopSync bool) {
+	k8sInstance := sync.k8sInstance
+	failedIntent := findFailedIntent(k8sInstance, intentType)
+	if failedIntent == nil {
+		return false
+	}
+	if failedIntent.Terminal {
+		sync.logger.Info("Intent has failed and will not be requested again until the spec changes or the retry annotation is changed", "intentType", intentType)
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionTrue, ConditionReasons.RetriesExhausted,
+			fmt.Sprintf("%s failed %d time(s), it will not be requested again until the spec changes or %s", intentType, failedIntent.Failures, retryAnnotationDescription))
+		sync.needsStatusUpdate = true
+		sync.stopSync = true
+		return sync.stopSync
+	}
+	if failedIntent.NextRetryTime != nil {
+		wait := time.Until(failedIntent.NextRetryTime.Time)
+		if wait > 0 {
+			sync.logger.Info("Intent has failed, will retry after backoff", "intentType", intentType, "nextRetryTime", failedIntent.NextRetryTime.Time)
+			setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Degraded, corev1.ConditionTrue, ConditionReasons.RetryScheduled,
+				fmt.Sprintf("%s failed %d time(s), will be requested again at %s", intentType, failedIntent.Failures, failedIntent.NextRetryTime.UTC().Format(time.RFC3339)))
+			sync.needsStatusUpdate = true
+			sync.requeue = true
+			sync.requeueDelay = wait
+			sync.stopSync = true
+			return sync.stopSync
+		}
+	}
+	sync.logger.Info("Retrying failed intent", "intentType", intentType, "failures", failedIntent.Failures)
+	return false
+}
@@ -0,0 +1,222 @@
+package assembly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/accanto/assembly-operator/internal/lm/fake"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// newTestSynchronizer returns a synchronizer for the Assembly which is not connected to Kubernetes or LM
+func newTestSynchronizer(instance *stratossv1alpha1.Assembly) *AssemblySynchronizer {
+	return &AssemblySynchronizer{
+		k8sInstance: instance,
+		original:    instance.DeepCopy(),
+		recorder:    record.NewFakeRecorder(100),
+		poller:      newProcessPoller(testPollSettings),
+		logger:      log,
+	}
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *stratossv1alpha1.RetryPolicy
+		failures int32
+		expected time.Duration
+	}{
+		{name: "default first retry", failures: 1, expected: 30 * time.Second},
+		{name: "default doubles", failures: 3, expected: 2 * time.Minute},
+		{name: "default limited to max delay", failures: 6, expected: 10 * time.Minute},
+		{
+			name:     "configured",
+			policy:   &stratossv1alpha1.RetryPolicy{Backoff: stratossv1alpha1.RetryBackoff{InitialDelay: &metav1.Duration{Duration: time.Second}, MaxDelay: &metav1.Duration{Duration: 5 * time.Second}}},
+			failures: 3,
+			expected: 4 * time.Second,
+		},
+		{
+			name:     "configured limited to max delay",
+			policy:   &stratossv1alpha1.RetryPolicy{Backoff: stratossv1alpha1.RetryBackoff{InitialDelay: &metav1.Duration{Duration: time.Second}, MaxDelay: &metav1.Duration{Duration: 5 * time.Second}}},
+			failures: 4,
+			expected: 5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := retrySettingsFor(stratossv1alpha1.AssemblySpec{RetryPolicy: tt.policy})
+			if delay := settings.delay(tt.failures); delay != tt.expected {
+				t.Errorf("Expected a delay of %s after %d failure(s) but was %s", tt.expected, tt.failures, delay)
+			}
+		})
+	}
+}
+
+func TestIntentOutcome(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             string
+		previousFailures int32
+		processStatus    string
+		expectFailures   int32
+		expectTerminal   bool
+		expectCleared    bool
+	}{
+		{name: "first failure", processStatus: "Failed", expectFailures: 1},
+		{name: "last retry", previousFailures: 1, processStatus: "Failed", expectFailures: 2},
+		{name: "retries exhausted", previousFailures: 2, processStatus: "Failed", expectFailures: 3, expectTerminal: true},
+		{name: "stop after failure", mode: stratossv1alpha1.RetryModes.StopAfterFailure, processStatus: "Failed", expectFailures: 1, expectTerminal: true},
+		{name: "completed", previousFailures: 2, processStatus: "Completed", expectCleared: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := activeSpec()
+			spec.RetryPolicy = &stratossv1alpha1.RetryPolicy{Mode: tt.mode, MaxRetries: int32Ptr(2)}
+			instance := newTestAssembly("a1", spec)
+			if tt.previousFailures > 0 {
+				instance.Status.FailedIntents = []stratossv1alpha1.FailedIntent{{IntentType: "Update", Failures: tt.previousFailures, SpecHash: "old"}}
+			}
+			instance.Status.LastProcess = stratossv1alpha1.Process{ID: "p1", IntentType: "Update", Status: tt.processStatus, DesiredStateHash: "requested"}
+			sync := newTestSynchronizer(instance)
+
+			before := time.Now()
+			sync.onIntentOutcome()
+			failedIntent := findFailedIntent(instance, "Update")
+			if tt.expectCleared {
+				if failedIntent != nil {
+					t.Errorf("Expected the failed intent to be cleared but was %+v", failedIntent)
+				}
+				return
+			}
+			if failedIntent == nil {
+				t.Fatalf("Expected a failed intent to be recorded")
+			}
+			if failedIntent.Failures != tt.expectFailures || failedIntent.Terminal != tt.expectTerminal {
+				t.Errorf("Expected %d failure(s) and terminal %t but was %+v", tt.expectFailures, tt.expectTerminal, failedIntent)
+			}
+			if failedIntent.SpecHash != "requested" {
+				t.Errorf("Expected the failure to be attributed to the desired state the intent was requested for but was %s", failedIntent.SpecHash)
+			}
+			if tt.expectTerminal {
+				if failedIntent.NextRetryTime != nil {
+					t.Errorf("Expected no next retry time once terminal but was %s", failedIntent.NextRetryTime)
+				}
+				return
+			}
+			delay := retrySettingsFor(spec).delay(tt.expectFailures)
+			if failedIntent.NextRetryTime == nil || failedIntent.NextRetryTime.Time.Before(before.Add(delay)) || failedIntent.NextRetryTime.Time.After(time.Now().Add(delay+time.Second)) {
+				t.Errorf("Expected the next retry to be in %s but was %v", delay, failedIntent.NextRetryTime)
+			}
+		})
+	}
+}
+
+func TestClearFailedIntents(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(sync *AssemblySynchronizer)
+		expectCleared bool
+	}{
+		{name: "unchanged", change: func(sync *AssemblySynchronizer) {}},
+		{name: "suspended", change: func(sync *AssemblySynchronizer) { sync.k8sInstance.Spec.Suspend = true }},
+		{
+			name: "polling changed",
+			change: func(sync *AssemblySynchronizer) {
+				sync.k8sInstance.Spec.Polling = &stratossv1alpha1.PollingPolicy{ResyncPeriod: &metav1.Duration{Duration: time.Minute}}
+			},
+		},
+		{
+			name: "retry policy changed",
+			change: func(sync *AssemblySynchronizer) {
+				sync.k8sInstance.Spec.RetryPolicy = &stratossv1alpha1.RetryPolicy{MaxRetries: int32Ptr(5)}
+			},
+		},
+		{name: "property changed", change: func(sync *AssemblySynchronizer) { sync.k8sInstance.Spec.Properties["size"] = "large" }, expectCleared: true},
+		{name: "descriptor changed", change: func(sync *AssemblySynchronizer) { sync.k8sInstance.Spec.DescriptorName = "assembly::test::2.0" }, expectCleared: true},
+		{name: "referenced Secret changed", change: func(sync *AssemblySynchronizer) { sync.sourceVersions = []string{"Secret/creds/2"} }, expectCleared: true},
+		{
+			name: "retry annotation changed",
+			change: func(sync *AssemblySynchronizer) {
+				sync.k8sInstance.SetAnnotations(map[string]string{stratossv1alpha1.RetryAnnotation: "2"})
+			},
+			expectCleared: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTestAssembly("a1", activeSpec())
+			instance.SetAnnotations(map[string]string{stratossv1alpha1.RetryAnnotation: "1"})
+			sync := newTestSynchronizer(instance)
+			sync.sourceVersions = []string{"Secret/creds/1"}
+			instance.Status.FailedIntents = []stratossv1alpha1.FailedIntent{{IntentType: "Update", Failures: 3, Terminal: true, SpecHash: sync.desiredStateHash(), RetryAnnotation: "1"}}
+
+			tt.change(sync)
+			sync.clearFailedIntents()
+			if cleared := findFailedIntent(instance, "Update") == nil; cleared != tt.expectCleared {
+				t.Errorf("Expected failed intent cleared to be %t but failed intents are %+v", tt.expectCleared, instance.Status.FailedIntents)
+			}
+		})
+	}
+}
+
+func TestClearFailedIntentsAttributesUnknownDesiredState(t *testing.T) {
+	instance := newTestAssembly("a1", activeSpec())
+	instance.Status.FailedIntents = []stratossv1alpha1.FailedIntent{{IntentType: "Update", Failures: 1}}
+	sync := newTestSynchronizer(instance)
+
+	sync.clearFailedIntents()
+	failedIntent := findFailedIntent(instance, "Update")
+	if failedIntent == nil || failedIntent.SpecHash != sync.desiredStateHash() {
+		t.Errorf("Expected a failure without a hash to be kept and attributed to the current desired state but failed intents are %+v", instance.Status.FailedIntents)
+	}
+}
+
+func TestReconcileHoldsBackFailedIntent(t *testing.T) {
+	spec := activeSpec()
+	spec.RetryPolicy = &stratossv1alpha1.RetryPolicy{Mode: stratossv1alpha1.RetryModes.StopAfterFailure}
+	env := newTestEnv(t, newTestAssembly("a1", spec))
+	defer env.close()
+	env.completeImmediately()
+	env.reconcileUntil("a1", isActive)
+
+	env.lm.SetProcessLifecycle(fake.ChangeAssemblyStateIntent, fake.ProcessLifecycle{FinalStatus: "Failed", StatusReason: "boom"})
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+		instance.Spec.IntendedState = stratossv1alpha1.AssemblyStates.Inactive
+	})
+	env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+		return findFailedIntent(instance, "ChangeState") != nil
+	})
+
+	// Held back, including after a change to the spec which does not change the Assembly in LM
+	env.mustReconcile("a1")
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+		instance.Spec.Polling = &stratossv1alpha1.PollingPolicy{ResyncPeriod: &metav1.Duration{Duration: time.Minute}}
+	})
+	env.mustReconcile("a1")
+	if requests := env.lm.IntentRequests("changeAssemblyState"); len(requests) != 1 {
+		t.Fatalf("Expected the failed intent to be held back but found %d changeAssemblyState requests", len(requests))
+	}
+	instance := env.get("a1")
+	if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Degraded); status != corev1.ConditionTrue || reason != ConditionReasons.RetriesExhausted {
+		t.Errorf("Expected Degraded to be True (%s) but was %s (%s)", ConditionReasons.RetriesExhausted, status, reason)
+	}
+	if events := env.events(); !hasEvent(events, EventReasons.RetriesExhausted) {
+		t.Errorf("Expected a %s event but found %v", EventReasons.RetriesExhausted, events)
+	}
+
+	env.lm.SetProcessLifecycle(fake.ChangeAssemblyStateIntent, fake.ProcessLifecycle{})
+	env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+		instance.SetAnnotations(map[string]string{stratossv1alpha1.RetryAnnotation: "1"})
+	})
+	env.mustReconcile("a1")
+	if requests := env.lm.IntentRequests("changeAssemblyState"); len(requests) != 2 {
+		t.Errorf("Expected the intent to be requested again once the retry annotation changed but found %d changeAssemblyState requests", len(requests))
+	}
+}
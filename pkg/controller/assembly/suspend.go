@@ -0,0 +1,44 @@
+package assembly
+
+import (
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// suspendedBy returns what suspended reconciliation of the Assembly, or an empty string if it is not suspended
+func suspendedBy(instance *stratossv1alpha1.Assembly) string {
+	if instance.GetAnnotations()[stratossv1alpha1.PausedAnnotation] == "true" {
+		return "annotation " + stratossv1alpha1.PausedAnnotation
+	}
+	if instance.Spec.Suspend {
+		return "spec.suspend"
+	}
+	return ""
+}
+
+func (sync *AssemblySynchronizer) updateSuspendedCondition() {
+	k8sInstance := sync.k8sInstance
+	previous := findCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Suspended)
+	wasSuspended := previous != nil && previous.Status == corev1.ConditionTrue
+	if by := suspendedBy(k8sInstance); by != "" {
+		if !wasSuspended {
+			sync.recordNormalEvent(EventReasons.Suspended, "Reconciliation suspended by %s, no intents will be requested in LM", by)
+		}
+		setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Suspended, corev1.ConditionTrue, ConditionReasons.Suspended, "Reconciliation suspended by "+by)
+		return
+	}
+	if wasSuspended {
+		sync.recordNormalEvent(EventReasons.Resumed, "Reconciliation resumed")
+	}
+	setCondition(k8sInstance, stratossv1alpha1.ConditionTypes.Suspended, corev1.ConditionFalse, ConditionReasons.NotSuspended, "")
+}
+
+// checkSuspended stops the reconcile, after the status has been refreshed from LM, when the Assembly is suspended
+func (sync *AssemblySynchronizer) checkSuspended() (stopSync bool) {
+	if by := suspendedBy(sync.k8sInstance); by != "" {
+		sync.logger.Info("Reconciliation is suspended, skipping intents", "suspendedBy", by)
+		sync.stopSync = true
+		return sync.stopSync
+	}
+	return false
+}
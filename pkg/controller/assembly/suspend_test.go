@@ -0,0 +1,88 @@
+package assembly
+
+import (
+	"testing"
+
+	lm "github.com/accanto/assembly-operator/internal/lm"
+	stratossv1alpha1 "github.com/accanto/assembly-operator/pkg/apis/stratoss/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestReconcileSuspended(t *testing.T) {
+	tests := []struct {
+		name    string
+		suspend func(instance *stratossv1alpha1.Assembly, suspended bool)
+	}{
+		{
+			name: "spec.suspend",
+			suspend: func(instance *stratossv1alpha1.Assembly, suspended bool) {
+				instance.Spec.Suspend = suspended
+			},
+		},
+		{
+			name: "paused annotation",
+			suspend: func(instance *stratossv1alpha1.Assembly, suspended bool) {
+				if suspended {
+					instance.SetAnnotations(map[string]string{stratossv1alpha1.PausedAnnotation: "true"})
+				} else {
+					instance.SetAnnotations(nil)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, newTestAssembly("a1", activeSpec()))
+			defer env.close()
+			env.completeImmediately()
+			env.reconcileUntil("a1", isActive)
+			requests := env.intentRequests()
+			env.events()
+
+			env.edit("a1", func(instance *stratossv1alpha1.Assembly) {
+				tt.suspend(instance, true)
+				instance.Spec.Properties["size"] = "medium"
+			})
+			// Changed in LM whilst suspended
+			env.lm.AddAssembly(lm.Assembly{
+				ID:             env.get("a1").Status.ID,
+				Name:           "a1",
+				State:          stratossv1alpha1.AssemblyStates.Inactive,
+				DescriptorName: "assembly::test::1.0",
+				Properties:     []lm.AssemblyProperty{{Name: "size", Value: "large"}},
+			})
+			for i := 0; i < 3; i++ {
+				env.mustReconcile("a1")
+			}
+			if actual := env.intentRequests(); actual != requests {
+				t.Errorf("Expected no intents whilst suspended but found %d", actual-requests)
+			}
+			instance := env.get("a1")
+			if instance.Status.State != stratossv1alpha1.AssemblyStates.Inactive || instance.Status.Properties["size"] != "large" {
+				t.Errorf("Expected the status to be refreshed from LM whilst suspended but was %s with properties %v", instance.Status.State, instance.Status.Properties)
+			}
+			if status, reason := conditionStatus(instance, stratossv1alpha1.ConditionTypes.Suspended); status != corev1.ConditionTrue || reason != ConditionReasons.Suspended {
+				t.Errorf("Expected Suspended to be True (%s) but was %s (%s)", ConditionReasons.Suspended, status, reason)
+			}
+			events := env.events()
+			if count := countEvents(events, EventReasons.Suspended); count != 1 {
+				t.Errorf("Expected a single %s event but found %v", EventReasons.Suspended, events)
+			}
+
+			env.edit("a1", func(instance *stratossv1alpha1.Assembly) { tt.suspend(instance, false) })
+			env.reconcileUntil("a1", func(instance *stratossv1alpha1.Assembly) bool {
+				return instance.Status.Properties["size"] == "medium" && isActive(instance)
+			})
+			if status, reason := conditionStatus(env.get("a1"), stratossv1alpha1.ConditionTypes.Suspended); status != corev1.ConditionFalse {
+				t.Errorf("Expected Suspended to be False once resumed but was %s (%s)", status, reason)
+			}
+			events = env.events()
+			if count := countEvents(events, EventReasons.Resumed); count != 1 {
+				t.Errorf("Expected a single %s event but found %v", EventReasons.Resumed, events)
+			}
+			if hasEvent(events, EventReasons.Suspended) {
+				t.Errorf("Expected no further %s event once resumed but found %v", EventReasons.Suspended, events)
+			}
+		})
+	}
+}